@@ -0,0 +1,51 @@
+// Package automemlimit sets the Go memory limit to the cgroup memory limit on import:
+//
+//	import _ "gopkg.in/go-mixed/hwstats.v1/automemlimit"
+//
+// It calls hwstats.UpdateGoMemoryLimitToCgroup with hwstats.DefaultMemoryLimitRatio, which does nothing if
+// the GOMEMLIMIT environment var is set. The ratio and the reserve can be changed by the environment vars:
+//   - HWSTATS_MEMORY_LIMIT_RATIO: the ratio of the memory limit in (0, 1], eg: 0.8
+//   - HWSTATS_MEMORY_RESERVE: the bytes reserved for the non-Go memory, eg: 67108864
+//
+// It does nothing if the process doesn't run in a cgroup, or if an environment var is invalid, which is logged.
+package automemlimit
+
+import (
+	"fmt"
+	"gopkg.in/go-mixed/hwstats.v1"
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
+	"log"
+	"os"
+	"strconv"
+)
+
+func init() {
+	if !cgroup.RunInCgroup() {
+		return
+	}
+	ratio, reserve, err := parseEnv(os.Getenv("HWSTATS_MEMORY_LIMIT_RATIO"), os.Getenv("HWSTATS_MEMORY_RESERVE"))
+	if err != nil {
+		log.Printf("automemlimit: %v", err)
+		return
+	}
+	hwstats.UpdateGoMemoryLimitToCgroup(ratio, hwstats.WithMemoryReserve(reserve))
+}
+
+// parseEnv parses the values of HWSTATS_MEMORY_LIMIT_RATIO and HWSTATS_MEMORY_RESERVE.
+// An empty value means the default: hwstats.DefaultMemoryLimitRatio and no reserve.
+func parseEnv(ratioEnv, reserveEnv string) (ratio float64, reserve uint64, err error) {
+	ratio = hwstats.DefaultMemoryLimitRatio
+	if ratioEnv != "" {
+		if ratio, err = strconv.ParseFloat(ratioEnv, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid HWSTATS_MEMORY_LIMIT_RATIO %q: %w", ratioEnv, err)
+		} else if ratio <= 0 || ratio > 1 {
+			return 0, 0, fmt.Errorf("HWSTATS_MEMORY_LIMIT_RATIO %q isn't in (0, 1]", ratioEnv)
+		}
+	}
+	if reserveEnv != "" {
+		if reserve, err = strconv.ParseUint(reserveEnv, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid HWSTATS_MEMORY_RESERVE %q: %w", reserveEnv, err)
+		}
+	}
+	return ratio, reserve, nil
+}
@@ -0,0 +1,38 @@
+package automemlimit
+
+import (
+	"gopkg.in/go-mixed/hwstats.v1"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratioEnv    string
+		reserveEnv  string
+		wantRatio   float64
+		wantReserve uint64
+		wantErr     bool
+	}{
+		{"defaults", "", "", hwstats.DefaultMemoryLimitRatio, 0, false},
+		{"ratio and reserve", "0.8", "67108864", 0.8, 67108864, false},
+		{"ratio of 1", "1", "", 1, 0, false},
+		{"ratio above 1", "1.5", "", 0, 0, true},
+		{"zero ratio", "0", "", 0, 0, true},
+		{"negative ratio", "-1", "", 0, 0, true},
+		{"invalid ratio", "90%", "", 0, 0, true},
+		{"invalid reserve", "", "64MiB", 0, 0, true},
+		{"negative reserve", "", "-1", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratio, reserve, err := parseEnv(tt.ratioEnv, tt.reserveEnv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnv(%q, %q) error = %v, wantErr %v", tt.ratioEnv, tt.reserveEnv, err, tt.wantErr)
+			}
+			if ratio != tt.wantRatio || reserve != tt.wantReserve {
+				t.Errorf("parseEnv(%q, %q) = %v, %v, want %v, %v", tt.ratioEnv, tt.reserveEnv, ratio, reserve, tt.wantRatio, tt.wantReserve)
+			}
+		})
+	}
+}
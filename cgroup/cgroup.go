@@ -1,17 +1,47 @@
 package cgroup
 
+import "path"
+
 // Main code from https://github.com/VictoriaMetrics/VictoriaMetrics/tree/master/lib/cgroup
 
 // Knowledge: https://fabiokung.com/2014/03/13/memory-inside-linux-containers/
 
+// RunInDocker returns true if /.dockerenv exists, which is only created by docker,
+// see DetectContainer for the other runtimes.
 func RunInDocker() bool {
 	return runInDocker()
 }
 
 // RunInCgroup returns true if the current process is in a cgroup.
 // Otherwise, returns false.
+//
+// The cgroup path is "/" in a container with a private cgroup namespace, eg: docker run --cgroupns=private,
+// but the limits of the container still apply, so the namespace root is a cgroup as well.
 func RunInCgroup() bool {
-	path := CgroupPath()
+	return defaultReader.RunInCgroup()
+}
+
+// RunInCgroup returns true if the cgroup is not the root cgroup of the hierarchy, see RunInCgroup.
+func (c *Cgroup) RunInCgroup() bool {
+	path := c.CgroupPath()
+
+	return path != "/" || c.inCgroupNamespace()
+}
+
+// inCgroupNamespace returns true if the cgroup path "/" is the root of a cgroup namespace rather than the
+// root of the hierarchy.
+func (c *Cgroup) inCgroupNamespace() bool {
+	if c.r.Mode() == ModeUnified {
+		// the root cgroup of cgroup v2 has no cgroup.type
+		dir, err := c.cgroupDir("")
+		return err == nil && fileExists(path.Join(dir, "cgroup.type"))
+	}
+	// release_agent only exists in the root cgroup of a cgroup v1 hierarchy
+	dir, err := c.cgroupDir("memory")
+	return err == nil && fileExists(dir) && !fileExists(path.Join(dir, "release_agent"))
+}
 
-	return path != "/"
+// CgroupPath returns the path to the cgroup of the current process.
+func CgroupPath() string {
+	return defaultReader.CgroupPath()
 }
@@ -2,10 +2,19 @@ package cgroup
 
 import (
 	"os"
+	"path"
+	"syscall"
 )
 
-// CgroupPath returns the path to the cgroup of the current process.
-func CgroupPath() string {
+// See https://man7.org/linux/man-pages/man2/statfs.2.html
+const (
+	cgroupSuperMagic  = 0x27e0eb
+	cgroup2SuperMagic = 0x63677270
+	tmpfsMagic        = 0x01021994
+)
+
+// CgroupPath returns the path of the cgroup, see CgroupPath.
+func (c *Cgroup) CgroupPath() string {
 	// /proc/self/cgroup contains something like this:
 	// 15:name=systemd:/
 	// 14:misc:/
@@ -23,26 +32,30 @@ func CgroupPath() string {
 	// 2:cpu:/         // k8s is this: 5:cpu,cpuacct:/...
 	// 1:cpuset:/
 	// 0::/
-	content, err := os.ReadFile("/proc/self/cgroup")
+	paths, err := c.cgroupPaths()
 	if err != nil {
 		return "/"
 	}
 
-	// check memory first because it is the most common cgroup
-	cgroupPath, err := grepFirstMatch(string(content), "memory", 2, ":")
-	if err != nil {
+	// cgroup v2 has only one hierarchy: "0::/..."
+	if c.r.Mode() == ModeUnified {
+		if cgroupPath, ok := paths[""]; ok {
+			return cgroupPath
+		}
 		return "/"
-	} else if cgroupPath != "/" {
+	}
+
+	// check memory first because it is the most common cgroup
+	if cgroupPath, ok := paths["memory"]; ok && cgroupPath != "/" {
 		return cgroupPath
 	}
 
-	// check cpu/cpuset second because it is the second most common cgroup
-	cgroupPath, err = grepFirstMatch(string(content), "cpu", 2, ":")
-	if err != nil {
-		return "/"
+	// check cpu second because it is the second most common cgroup
+	if cgroupPath, ok := paths["cpu"]; ok {
+		return cgroupPath
 	}
 
-	return cgroupPath
+	return "/"
 }
 
 func runInDocker() bool {
@@ -52,3 +65,38 @@ func runInDocker() bool {
 
 	return false
 }
+
+// detectMode detects the cgroup mode from the mount table, or from the filesystem magic of /sys/fs/cgroup.
+func (r *Reader) detectMode(mounts []Mount) CgroupMode {
+	// the mount table tells which hierarchies are really mounted, even at custom mount points.
+	if m := modeFromMounts(mounts); m != ModeNone {
+		return m
+	}
+
+	// fallback to the filesystem magic of /sys/fs/cgroup, eg: /proc is not mounted.
+	magic, err := fsType(path.Join(r.sysRoot, "fs/cgroup"))
+	if err != nil {
+		return ModeNone
+	}
+	switch magic {
+	case cgroup2SuperMagic:
+		return ModeUnified
+	case cgroupSuperMagic:
+		return ModeLegacy
+	case tmpfsMagic:
+		// systemd mounts the v2 hierarchy at /sys/fs/cgroup/unified in hybrid mode
+		if magic, err = fsType(path.Join(r.sysRoot, "fs/cgroup/unified")); err == nil && magic == cgroup2SuperMagic {
+			return ModeHybrid
+		}
+		return ModeLegacy
+	}
+	return ModeNone
+}
+
+func fsType(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Type), nil
+}
@@ -2,11 +2,15 @@
 
 package cgroup
 
-// CgroupPath returns the path to the cgroup of the current process.
-func CgroupPath() string {
+// CgroupPath returns the path of the cgroup, see CgroupPath.
+func (c *Cgroup) CgroupPath() string {
 	return "/"
 }
 
 func runInDocker() bool {
 	return false
 }
+
+func (r *Reader) detectMode([]Mount) CgroupMode {
+	return ModeNone
+}
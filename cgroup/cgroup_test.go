@@ -0,0 +1,1078 @@
+//go:build linux
+
+package cgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+const hybridMountInfo = `24 29 0:22 / /sys rw,nosuid,nodev,noexec,relatime shared:7 - sysfs sysfs rw
+32 24 0:28 / /sys/fs/cgroup ro,nosuid,nodev,noexec shared:9 - tmpfs tmpfs ro,mode=755
+33 32 0:29 / /sys/fs/cgroup/unified rw,nosuid,nodev,noexec,relatime shared:10 - cgroup2 cgroup2 rw,nsdelegate
+34 32 0:30 / /sys/fs/cgroup/systemd rw,nosuid,nodev,noexec,relatime shared:11 - cgroup cgroup rw,xattr,name=systemd
+35 32 0:31 /docker/ab12 /sys/fs/cgroup/cpu,cpuacct rw,nosuid,nodev,noexec,relatime shared:16 - cgroup cgroup rw,cpu,cpuacct
+36 32 0:32 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:17 - cgroup cgroup rw,memory
+37 32 0:33 / /mnt/my\040cgroup rw,relatime - cgroup cgroup rw,pids`
+
+func TestParseMountInfo(t *testing.T) {
+	mounts := parseMountInfo(hybridMountInfo)
+	want := []Mount{
+		{MountPoint: "/sys/fs/cgroup/unified", Root: "/", FSType: "cgroup2"},
+		{MountPoint: "/sys/fs/cgroup/systemd", Root: "/", FSType: "cgroup", Controllers: []string{"name=systemd"}},
+		{MountPoint: "/sys/fs/cgroup/cpu,cpuacct", Root: "/docker/ab12", FSType: "cgroup", Controllers: []string{"cpu", "cpuacct"}},
+		{MountPoint: "/sys/fs/cgroup/memory", Root: "/", FSType: "cgroup", Controllers: []string{"memory"}},
+		{MountPoint: "/mnt/my cgroup", Root: "/", FSType: "cgroup", Controllers: []string{"pids"}},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Fatalf("parseMountInfo() = %+v, want %+v", mounts, want)
+	}
+	if m := modeFromMounts(mounts); m != ModeHybrid {
+		t.Errorf("modeFromMounts() = %v, want %v", m, ModeHybrid)
+	}
+	if m := modeFromMounts(mounts[:1]); m != ModeUnified {
+		t.Errorf("modeFromMounts() = %v, want %v", m, ModeUnified)
+	}
+	if m := modeFromMounts(mounts[1:]); m != ModeLegacy {
+		t.Errorf("modeFromMounts() = %v, want %v", m, ModeLegacy)
+	}
+}
+
+func TestControllerDir(t *testing.T) {
+	mounts := parseMountInfo(hybridMountInfo)
+	paths := parseProcCgroup("5:cpu,cpuacct:/docker/ab12\n4:memory:/docker/ab12\n3:pids:/docker/ab12\n0::/docker/ab12:x\n")
+
+	tests := []struct {
+		controller string
+		want       string
+	}{
+		{"", "/sys/fs/cgroup/unified/docker/ab12:x"},
+		{"cpu", "/sys/fs/cgroup/cpu,cpuacct"},
+		{"cpuacct", "/sys/fs/cgroup/cpu,cpuacct"},
+		{"memory", "/sys/fs/cgroup/memory/docker/ab12"},
+		{"pids", "/mnt/my cgroup/docker/ab12"},
+	}
+	for _, tt := range tests {
+		got, err := controllerDir(mounts, tt.controller, paths[tt.controller])
+		if err != nil {
+			t.Errorf("controllerDir(%q) error: %v", tt.controller, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("controllerDir(%q) = %q, want %q", tt.controller, got, tt.want)
+		}
+	}
+
+	if _, err := controllerDir(mounts, "blkio", "/"); err == nil {
+		t.Errorf("controllerDir(%q) should fail", "blkio")
+	}
+}
+
+// writeFiles writes the files into the fixture directory dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newUnifiedReader returns a Reader of a fixture host in unified mode, the current process is in /app.slice/app.
+func newUnifiedReader(t *testing.T, files map[string]string) *Reader {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":    "0::/app.slice/app\n",
+		"proc/self/mountinfo": "32 24 0:28 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:9 - cgroup2 cgroup2 rw,nsdelegate\n",
+	})
+	writeFiles(t, dir, files)
+	return NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+}
+
+// v1MountInfo mounts the cgroup v1 controllers at /sys/fs/cgroup/<controller>.
+const v1MountInfo = `30 24 0:26 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:8 - cgroup cgroup rw,memory
+31 24 0:27 / /sys/fs/cgroup/cpu,cpuacct rw,nosuid,nodev,noexec,relatime shared:9 - cgroup cgroup rw,cpu,cpuacct
+32 24 0:28 / /sys/fs/cgroup/cpuset rw,nosuid,nodev,noexec,relatime shared:10 - cgroup cgroup rw,cpuset
+33 24 0:29 / /sys/fs/cgroup/blkio rw,nosuid,nodev,noexec,relatime shared:11 - cgroup cgroup rw,blkio
+34 24 0:30 / /sys/fs/cgroup/pids rw,nosuid,nodev,noexec,relatime shared:12 - cgroup cgroup rw,pids
+`
+
+// newV1Reader returns a Reader of a fixture host in legacy mode, or in hybrid mode with the cgroup v2 hierarchy
+// mounted at /sys/fs/cgroup/unified. The current process is in /app of all the hierarchies.
+func newV1Reader(t *testing.T, hybrid bool, files map[string]string) *Reader {
+	dir := t.TempDir()
+	mountInfo := v1MountInfo
+	procCgroup := "6:pids:/app\n5:blkio:/app\n4:memory:/app\n3:cpuset:/app\n2:cpu,cpuacct:/app\n"
+	if hybrid {
+		mountInfo += "35 24 0:31 / /sys/fs/cgroup/unified rw,nosuid,nodev,noexec,relatime shared:13 - cgroup2 cgroup2 rw,nsdelegate\n"
+		procCgroup += "0::/app\n"
+	}
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":    procCgroup,
+		"proc/self/mountinfo": mountInfo,
+	})
+	writeFiles(t, dir, files)
+	return NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+}
+
+func TestV1Reader(t *testing.T) {
+	files := map[string]string{
+		"sys/fs/cgroup/memory/app/memory.limit_in_bytes":   "1073741824\n",
+		"sys/fs/cgroup/memory/app/memory.usage_in_bytes":   "524288\n",
+		"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_quota_us":   "250000\n",
+		"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_period_us":  "100000\n",
+		"sys/fs/cgroup/cpuset/app/cpuset.effective_cpus":   "0-3\n",
+		"sys/fs/cgroup/unified/app/memory.max":             "2147483648\n",
+		"sys/fs/cgroup/unified/app/memory.current":         "1\n",
+		"sys/fs/cgroup/unified/app/cpu.max":                "50000 100000\n",
+		"sys/fs/cgroup/unified/app/memory.pressure":        "some avg10=1.00 avg60=0.00 avg300=0.00 total=100\n",
+		"sys/fs/cgroup/unified/app/cgroup.type":            "domain\n",
+		"sys/fs/cgroup/unified/app/cgroup.controllers":     "\n",
+		"sys/fs/cgroup/unified/app/cgroup.subtree_control": "\n",
+	}
+	for _, tt := range []struct {
+		hybrid bool
+		mode   CgroupMode
+	}{
+		{false, ModeLegacy},
+		{true, ModeHybrid},
+	} {
+		r := newV1Reader(t, tt.hybrid, files)
+		if m := r.Mode(); m != tt.mode {
+			t.Errorf("Mode() = %v, want %v", m, tt.mode)
+		}
+		// the limits are read from the v1 controllers, the v2 hierarchy of hybrid mode has no controller
+		if p := r.CgroupPath(); p != "/app" {
+			t.Errorf("%v: CgroupPath() = %q, want %q", tt.mode, p, "/app")
+		}
+		if n := r.GetMemoryLimit(); n != 1073741824 {
+			t.Errorf("%v: GetMemoryLimit() = %d, want %d", tt.mode, n, 1073741824)
+		}
+		if n := r.GetMemoryUsage(); n != 524288 {
+			t.Errorf("%v: GetMemoryUsage() = %d, want %d", tt.mode, n, 524288)
+		}
+		quota, source := r.ExplainCPUQuota()
+		if want := (LimitSource{Origin: OriginCgroup, Path: "/app", File: "cpu.cfs_quota_us"}); quota != 2.5 || source != want {
+			t.Errorf("%v: ExplainCPUQuota() = %f, %+v, want %f, %+v", tt.mode, quota, source, 2.5, want)
+		}
+		if n := r.GetCPUSet().Count(); n != 4 {
+			t.Errorf("%v: GetCPUSet().Count() = %d, want %d", tt.mode, n, 4)
+		}
+		// the pressure is only in the v2 hierarchy
+		pressure, err := r.GetPressure(PressureMemory)
+		if tt.hybrid && (err != nil || pressure.Some.Total != 100) {
+			t.Errorf("%v: GetPressure() = %+v, %v, want total %d", tt.mode, pressure, err, 100)
+		}
+		if !tt.hybrid && err == nil {
+			t.Errorf("%v: GetPressure() should fail", tt.mode)
+		}
+	}
+}
+
+func TestReaderRefresh(t *testing.T) {
+	r := newV1Reader(t, false, nil)
+	if m := r.Mode(); m != ModeLegacy {
+		t.Fatalf("Mode() = %v, want %v", m, ModeLegacy)
+	}
+	writeFiles(t, r.ProcRoot(), map[string]string{
+		"self/mountinfo": "32 24 0:28 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:9 - cgroup2 cgroup2 rw\n",
+	})
+	// the mount table is cached
+	if m := r.Mode(); m != ModeLegacy {
+		t.Errorf("Mode() = %v, want the cached %v", m, ModeLegacy)
+	}
+	r.Refresh()
+	if m := r.Mode(); m != ModeUnified {
+		t.Errorf("Mode() after Refresh() = %v, want %v", m, ModeUnified)
+	}
+	if mounts, err := r.Mounts(); err != nil || len(mounts) != 1 || !mounts[0].IsV2() {
+		t.Errorf("Mounts() after Refresh() = %+v, %v, want the cgroup2 mount", mounts, err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/memory.max":     "1073741824\n",
+		"sys/fs/cgroup/app.slice/app/memory.current": "524288\n",
+		"sys/fs/cgroup/app.slice/app/cpu.max":        "150000 100000\n",
+		"sys/fs/cgroup/app.slice/app/cpu.stat":       "usage_usec 3000\nuser_usec 2000\nsystem_usec 1000\nnr_periods 10\nnr_throttled 4\nthrottled_usec 800\n",
+	})
+
+	if m := r.Mode(); m != ModeUnified {
+		t.Errorf("Mode() = %v, want %v", m, ModeUnified)
+	}
+	if p := r.CgroupPath(); p != "/app.slice/app" {
+		t.Errorf("CgroupPath() = %q, want %q", p, "/app.slice/app")
+	}
+	if n := r.GetMemoryLimit(); n != 1073741824 {
+		t.Errorf("GetMemoryLimit() = %d, want %d", n, 1073741824)
+	}
+	if n := r.GetMemoryUsage(); n != 524288 {
+		t.Errorf("GetMemoryUsage() = %d, want %d", n, 524288)
+	}
+	if n := r.GetCPUQuota(); n != 1.5 {
+		t.Errorf("GetCPUQuota() = %f, want %f", n, 1.5)
+	}
+	stat, err := r.GetCPUStat()
+	if err != nil {
+		t.Fatalf("GetCPUStat() error: %v", err)
+	}
+	if want := (CPUStat{UsageUsec: 3000, UserUsec: 2000, SystemUsec: 1000, NrPeriods: 10, NrThrottled: 4, ThrottledUsec: 800}); *stat != want {
+		t.Errorf("GetCPUStat() = %+v, want %+v", *stat, want)
+	}
+}
+
+func TestParseCPUSet(t *testing.T) {
+	for _, tt := range []struct {
+		data   string
+		want   CPUSet
+		string string
+	}{
+		{"", CPUSet{}, ""},
+		{"0\n", CPUSet{0}, "0"},
+		{"0-3,8,10-11", CPUSet{0, 1, 2, 3, 8, 10, 11}, "0-3,8,10-11"},
+		{"4,0-2,1", CPUSet{0, 1, 2, 4}, "0-2,4"},
+		{"65535", CPUSet{65535}, "65535"},
+	} {
+		cpus, err := ParseCPUSet(tt.data)
+		if err != nil {
+			t.Errorf("ParseCPUSet(%q) error: %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(cpus, tt.want) {
+			t.Errorf("ParseCPUSet(%q) = %v, want %v", tt.data, []int(cpus), []int(tt.want))
+		}
+		if s := cpus.String(); s != tt.string {
+			t.Errorf("ParseCPUSet(%q).String() = %q, want %q", tt.data, s, tt.string)
+		}
+	}
+	for _, data := range []string{"a", "1-", "3-1", "-1", "1,,2", "1-2-3", "0-2000000000", "65536"} {
+		if _, err := ParseCPUSet(data); err == nil {
+			t.Errorf("ParseCPUSet(%q) want error", data)
+		}
+	}
+
+	cpus := CPUSet{0, 1, 2, 4}
+	if !cpus.Contains(4) || cpus.Contains(3) || cpus.Count() != 4 {
+		t.Errorf("CPUSet %v Contains/Count mismatch", cpus)
+	}
+
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/cpu.max":               "400000 100000\n",
+		"sys/fs/cgroup/app.slice/app/cpuset.cpus.effective": "2-3\n",
+		"sys/fs/cgroup/app.slice/app/cpuset.mems.effective": "0\n",
+	})
+	if s := r.GetCPUSet().String(); s != "2-3" {
+		t.Errorf("GetCPUSet() = %q, want %q", s, "2-3")
+	}
+	if s := r.GetCPUSetMems().String(); s != "0" {
+		t.Errorf("GetCPUSetMems() = %q, want %q", s, "0")
+	}
+	if n := r.GetCPUQuota(); n != 2 {
+		t.Errorf("GetCPUQuota() = %f, want %f", n, 2.0)
+	}
+}
+
+func TestCPUWeight(t *testing.T) {
+	// the conversion is lossy, 1024 shares -> 39 weight -> 998 shares
+	for _, tt := range []struct{ shares, weight, back int64 }{
+		{2, 1, 2},
+		{1024, 39, 998},
+		{262144, 10000, 262144},
+	} {
+		if w := CPUSharesToWeight(tt.shares); w != tt.weight {
+			t.Errorf("CPUSharesToWeight(%d) = %d, want %d", tt.shares, w, tt.weight)
+		}
+		if s := CPUWeightToShares(tt.weight); s != tt.back {
+			t.Errorf("CPUWeightToShares(%d) = %d, want %d", tt.weight, s, tt.back)
+		}
+	}
+
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/cpu.weight":      "100\n",
+		"sys/fs/cgroup/app.slice/app/cpu.weight.nice": "-3\n",
+	})
+	w, err := r.GetCPUWeight()
+	if err != nil {
+		t.Fatalf("GetCPUWeight() error: %v", err)
+	}
+	if want := (CPUWeight{Weight: 100, Shares: 2597, Nice: -3}); *w != want {
+		t.Errorf("GetCPUWeight() = %+v, want %+v", *w, want)
+	}
+}
+
+func TestEffectiveLimits(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/memory.max":      "536870912\n",
+		"sys/fs/cgroup/app.slice/memory.high":     "max\n",
+		"sys/fs/cgroup/app.slice/cpu.max":         "200000 100000\n",
+		"sys/fs/cgroup/app.slice/app/memory.max":  "1073741824\n",
+		"sys/fs/cgroup/app.slice/app/memory.high": "805306368\n",
+		"sys/fs/cgroup/app.slice/app/cpu.max":     "max 100000\n",
+	})
+
+	if n, p := r.EffectiveMemoryLimit(); n != 536870912 || p != "/app.slice" {
+		t.Errorf("EffectiveMemoryLimit() = %d, %q, want %d, %q", n, p, 536870912, "/app.slice")
+	}
+	if n, p := r.EffectiveMemoryHigh(); n != 805306368 || p != "/app.slice/app" {
+		t.Errorf("EffectiveMemoryHigh() = %d, %q, want %d, %q", n, p, 805306368, "/app.slice/app")
+	}
+	if n, p := r.EffectiveCPUQuota(); n != 2 || p != "/app.slice" {
+		t.Errorf("EffectiveCPUQuota() = %f, %q, want %f, %q", n, p, 2.0, "/app.slice")
+	}
+	if n := r.GetCPUQuota(); n != 2 {
+		t.Errorf("GetCPUQuota() = %f, want %f", n, 2.0)
+	}
+
+	r = newUnifiedReader(t, nil)
+	if n, p := r.EffectiveMemoryLimit(); n != math.MaxInt64 || p != "" {
+		t.Errorf("EffectiveMemoryLimit() = %d, %q, want no limit", n, p)
+	}
+	if n, p := r.EffectiveCPUQuota(); n != -1 || p != "" {
+		t.Errorf("EffectiveCPUQuota() = %f, %q, want no quota", n, p)
+	}
+}
+
+func TestMemoryLimits(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/memory.max":          "536870912\n",
+		"sys/fs/cgroup/app.slice/app/memory.min":      "0\n",
+		"sys/fs/cgroup/app.slice/app/memory.low":      "134217728\n",
+		"sys/fs/cgroup/app.slice/app/memory.high":     "805306368\n",
+		"sys/fs/cgroup/app.slice/app/memory.max":      "max\n",
+		"sys/fs/cgroup/app.slice/app/memory.swap.max": "0\n",
+	})
+	limits, err := r.GetMemoryLimits()
+	if err != nil {
+		t.Fatalf("GetMemoryLimits() error: %v", err)
+	}
+	if want := (MemoryLimits{Low: 134217728, High: 805306368, Max: Unlimited, SoftLimit: Unlimited}); *limits != want {
+		t.Errorf("GetMemoryLimits() = %+v, want %+v", *limits, want)
+	}
+	if n := r.GetMemoryHierarchicalLimit(); n != 536870912 {
+		t.Errorf("GetMemoryHierarchicalLimit() = %d, want %d", n, 536870912)
+	}
+	if n := r.GetHierarchicalMemoryLimit(); n != 536870912 {
+		t.Errorf("GetHierarchicalMemoryLimit() = %d, want %d", n, 536870912)
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":                                    "4:memory:/app\n",
+		"proc/self/mountinfo":                                 "30 24 0:26 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:8 - cgroup cgroup rw,memory\n",
+		"sys/fs/cgroup/memory/app/memory.limit_in_bytes":      "9223372036854771712\n",
+		"sys/fs/cgroup/memory/app/memory.soft_limit_in_bytes": "268435456\n",
+		"sys/fs/cgroup/memory/app/memory.stat":                "cache 0\nhierarchical_memory_limit 9223372036854771712\n",
+	})
+	r = NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+	limits, err = r.GetMemoryLimits()
+	if err != nil {
+		t.Fatalf("GetMemoryLimits() error: %v", err)
+	}
+	if want := (MemoryLimits{High: Unlimited, Max: Unlimited, SwapMax: Unlimited, SoftLimit: 268435456}); *limits != want {
+		t.Errorf("GetMemoryLimits() = %+v, want %+v", *limits, want)
+	}
+	if n := r.GetMemoryLimit(); n != Unlimited {
+		t.Errorf("GetMemoryLimit() = %d, want Unlimited", n)
+	}
+	if n := r.GetHierarchicalMemoryLimit(); n != Unlimited {
+		t.Errorf("GetHierarchicalMemoryLimit() = %d, want Unlimited", n)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/memory.swap.max":     "max\n",
+		"sys/fs/cgroup/app.slice/app/memory.swap.current": "4096\n",
+	})
+	if n, err := r.GetSwapLimit(); err != nil || n != math.MaxInt64 {
+		t.Errorf("GetSwapLimit() = %d, %v, want %d", n, err, int64(math.MaxInt64))
+	}
+	if n, err := r.GetSwapUsage(); err != nil || n != 4096 {
+		t.Errorf("GetSwapUsage() = %d, %v, want %d", n, err, 4096)
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":                                     "4:memory:/app\n",
+		"proc/self/mountinfo":                                  "30 24 0:26 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:8 - cgroup cgroup rw,memory\n",
+		"sys/fs/cgroup/memory/app/memory.limit_in_bytes":       "1073741824\n",
+		"sys/fs/cgroup/memory/app/memory.memsw.limit_in_bytes": "1073741824\n",
+		"sys/fs/cgroup/memory/app/memory.usage_in_bytes":       "8192\n",
+		"sys/fs/cgroup/memory/app/memory.memsw.usage_in_bytes": "8192\n",
+	})
+	r = NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+	if n, err := r.GetSwapLimit(); err != nil || n != 0 {
+		t.Errorf("GetSwapLimit() = %d, %v, want 0", n, err)
+	}
+	if n, err := r.GetSwapUsage(); err != nil || n != 0 {
+		t.Errorf("GetSwapUsage() = %d, %v, want 0", n, err)
+	}
+}
+
+func TestWatchLimits(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/cpu.max":    "100000 100000\n",
+		"sys/fs/cgroup/app.slice/app/memory.max": "1073741824\n",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := r.WatchLimits(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchLimits() error: %v", err)
+	}
+
+	writeFiles(t, filepath.Dir(r.ProcRoot()), map[string]string{
+		"sys/fs/cgroup/app.slice/app/cpu.max": "200000 100000\n",
+	})
+	select {
+	case change := <-ch:
+		want := LimitChange{
+			Old: Limits{CPUQuota: 1, MemoryLimit: 1073741824},
+			New: Limits{CPUQuota: 2, MemoryLimit: 1073741824},
+		}
+		if change != want {
+			t.Errorf("WatchLimits() = %+v, want %+v", change, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchLimits() timeout")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestManager(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/cgroup.procs": "",
+	})
+	m, err := r.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	if p := m.Path(); p != "/app.slice/app" {
+		t.Errorf("Path() = %q, want %q", p, "/app.slice/app")
+	}
+	if err := m.EnableControllers("memory", "cpu", "pids"); err != nil {
+		t.Fatalf("EnableControllers() error: %v", err)
+	}
+
+	child, err := m.NewChild("job-1")
+	if err != nil {
+		t.Fatalf("NewChild() error: %v", err)
+	}
+	if _, err := m.NewChild("job-1"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("NewChild() of an existing child error = %v, want %v", err, os.ErrExist)
+	}
+	if _, err := m.NewChild("../job-2"); err == nil {
+		t.Errorf("NewChild(%q) want error", "../job-2")
+	}
+	if p := child.Path(); p != "/app.slice/app/job-1" {
+		t.Errorf("child Path() = %q, want %q", p, "/app.slice/app/job-1")
+	}
+	for _, err := range []error{
+		child.SetMemoryMax(1 << 30),
+		child.SetMemoryHigh(Unlimited),
+		child.SetCPUMax(1.5, 0),
+		child.SetPidsMax(64),
+		child.AddProc(1234),
+		child.Freeze(),
+	} {
+		if err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+	}
+	for file, want := range map[string]string{
+		"app.slice/app/cgroup.subtree_control": "+memory +cpu +pids",
+		"app.slice/app/job-1/memory.max":       "1073741824",
+		"app.slice/app/job-1/memory.high":      "max",
+		"app.slice/app/job-1/cpu.max":          "150000 100000",
+		"app.slice/app/job-1/pids.max":         "64",
+		"app.slice/app/job-1/cgroup.procs":     "1234",
+		"app.slice/app/job-1/cgroup.freeze":    "1",
+	} {
+		data, err := os.ReadFile(filepath.Join(r.SysRoot(), "fs/cgroup", file))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", file, data, err, want)
+		}
+	}
+	if n := child.GetMemoryLimit(); n != 1<<30 {
+		t.Errorf("child GetMemoryLimit() = %d, want %d", n, 1<<30)
+	}
+	if pids, err := child.Procs(); err != nil || !reflect.DeepEqual(pids, []int{1234}) {
+		t.Errorf("child Procs() = %v, %v, want [1234]", pids, err)
+	}
+	if names, err := m.Children(); err != nil || !reflect.DeepEqual(names, []string{"job-1"}) {
+		t.Errorf("Children() = %v, %v, want [job-1]", names, err)
+	}
+
+	// the interface files are removed with the directory by the kernel
+	for _, file := range []string{"memory.max", "memory.high", "cpu.max", "pids.max", "cgroup.procs", "cgroup.freeze"} {
+		if err := os.Remove(filepath.Join(child.Dir(), file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := child.Remove(); err != nil {
+		t.Errorf("Remove() error: %v", err)
+	}
+	if _, err := m.Child("job-1"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Child() of a removed child error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestManagerPinned(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/cgroup.procs":   "",
+		"sys/fs/cgroup/app.slice/app/cgroup.events":  "populated 1\nfrozen 0\n",
+		"sys/fs/cgroup/app.slice/app/memory.current": "4096\n",
+	})
+	m, err := r.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	leaf, err := m.NewChild("leaf")
+	if err != nil {
+		t.Fatalf("NewChild() error: %v", err)
+	}
+	// the process moves itself into the leaf, as the kernel does for the writes of cgroup.procs
+	if err := leaf.AddProc(os.Getpid()); err != nil {
+		t.Fatalf("AddProc() error: %v", err)
+	}
+	writeFiles(t, leaf.Dir(), map[string]string{
+		"cgroup.events":  "populated 1\nfrozen 1\n",
+		"memory.current": "1024\n",
+	})
+	writeFiles(t, r.ProcRoot(), map[string]string{"self/cgroup": "0::/app.slice/app/leaf\n"})
+	if p := r.CgroupPath(); p != "/app.slice/app/leaf" {
+		t.Fatalf("CgroupPath() = %q, want %q", p, "/app.slice/app/leaf")
+	}
+
+	// the manager still reads the cgroup it was created for
+	if frozen, err := m.Frozen(); err != nil || frozen {
+		t.Errorf("Frozen() = %v, %v, want false", frozen, err)
+	}
+	if frozen, err := leaf.Frozen(); err != nil || !frozen {
+		t.Errorf("leaf Frozen() = %v, %v, want true", frozen, err)
+	}
+	if n := m.GetMemoryUsage(); n != 4096 {
+		t.Errorf("GetMemoryUsage() = %d, want %d", n, 4096)
+	}
+	if p := m.CgroupPath(); p != "/app.slice/app" {
+		t.Errorf("CgroupPath() = %q, want %q", p, "/app.slice/app")
+	}
+	if n := leaf.GetMemoryUsage(); n != 1024 {
+		t.Errorf("leaf GetMemoryUsage() = %d, want %d", n, 1024)
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	const id = "3f4c8a0e1d2b5a6c7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d"
+	for _, tt := range []struct {
+		cgroup string
+		want   ContainerInfo
+	}{
+		{"0::/user.slice/user-1000.slice/session-2.scope\n", ContainerInfo{}},
+		{"0::/system.slice/docker.service\n", ContainerInfo{}},
+		{"12:memory:/docker/" + id + "\n", ContainerInfo{Runtime: RuntimeDocker, ID: id}},
+		{"0::/system.slice/docker-" + id + ".scope\n", ContainerInfo{Runtime: RuntimeDocker, ID: id}},
+		{
+			"0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod0a1b2c3d_4e5f_6a7b_8c9d_0e1f2a3b4c5d.slice/cri-containerd-" + id + ".scope\n",
+			ContainerInfo{Runtime: RuntimeContainerd, ID: id, PodUID: "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d"},
+		},
+		{
+			"0::/kubepods.slice/kubepods-pod0a1b2c3d_4e5f_6a7b_8c9d_0e1f2a3b4c5d.slice/crio-" + id + ".scope\n",
+			ContainerInfo{Runtime: RuntimeCRIO, ID: id, PodUID: "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d"},
+		},
+		{
+			"4:memory:/kubepods/besteffort/pod0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d/" + id + "\n",
+			ContainerInfo{Runtime: RuntimeUnknown, ID: id, PodUID: "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d"},
+		},
+		{"0::/machine.slice/libpod-" + id + ".scope/container\n", ContainerInfo{Runtime: RuntimePodman, ID: id}},
+		{"4:memory:/ecs/0123456789abcdef/" + id + "\n", ContainerInfo{Runtime: RuntimeECS, ID: id}},
+		{"0::/lxc.payload.web/system.slice\n", ContainerInfo{Runtime: RuntimeLXC, ID: "web"}},
+	} {
+		if info := detectContainerFromCgroup(tt.cgroup); info != tt.want {
+			t.Errorf("detectContainerFromCgroup(%q) = %+v, want %+v", tt.cgroup, info, tt.want)
+		}
+	}
+
+	// the cgroup namespace hides the cgroup path, the bind mounts of the runtime are visible
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup": "0::/\n",
+		"proc/self/mountinfo": "" +
+			"640 620 259:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/nvme0n1p1 rw\n" +
+			"641 620 259:1 /var/lib/kubelet/pods/0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d/etc-hosts /etc/hosts rw,relatime - ext4 /dev/nvme0n1p1 rw\n",
+	})
+	r := NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+	if info, want := r.DetectContainer(), (ContainerInfo{Runtime: RuntimeDocker, ID: id, PodUID: "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d"}); info != want {
+		t.Errorf("DetectContainer() = %+v, want %+v", info, want)
+	}
+
+	dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":    "0::/\n",
+		"proc/1/environ":      "PATH=/usr/bin\x00container=systemd-nspawn\x00",
+		"run/.containerenv":   "",
+		"proc/self/mountinfo": "",
+	})
+	r = NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+	if info := r.DetectContainer(); info.Runtime != RuntimePodman {
+		t.Errorf("DetectContainer() = %+v, want %v", info, RuntimePodman)
+	}
+	if err := os.Remove(filepath.Join(dir, "run/.containerenv")); err != nil {
+		t.Fatal(err)
+	}
+	if info := r.DetectContainer(); info.Runtime != RuntimeNspawn {
+		t.Errorf("DetectContainer() = %+v, want %v", info, RuntimeNspawn)
+	}
+
+	// the environ of pid 1 is read under procRoot, not from the calling process
+	writeFiles(t, dir, map[string]string{
+		"proc/1/environ": "container=oci\x00ECS_CONTAINER_METADATA_URI_V4=http://169.254.170.2/v4/" + id + "\x00",
+	})
+	if info := r.DetectContainer(); info.Runtime != RuntimeECS {
+		t.Errorf("DetectContainer() = %+v, want %v", info, RuntimeECS)
+	}
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "http://169.254.170.2/v4/"+id)
+	writeFiles(t, dir, map[string]string{"proc/1/environ": "PATH=/usr/bin\x00"})
+	if info := r.DetectContainer(); info.Runtime == RuntimeECS {
+		t.Errorf("DetectContainer() = %+v from the environment of the calling process", info)
+	}
+}
+
+func TestCgroupNamespace(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":         "0::/\n",
+		"proc/self/mountinfo":      "32 24 0:28 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime - cgroup2 cgroup2 rw\n",
+		"sys/fs/cgroup/memory.max": "536870912\n",
+	})
+	r := NewReader(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+	// the root cgroup of the host
+	if r.RunInCgroup() {
+		t.Errorf("RunInCgroup() = true without cgroup.type, want false")
+	}
+
+	// the root of the cgroup namespace of a container
+	writeFiles(t, dir, map[string]string{
+		"sys/fs/cgroup/cgroup.type": "domain\n",
+	})
+	if !r.RunInCgroup() {
+		t.Errorf("RunInCgroup() = false with cgroup.type, want true")
+	}
+	n, source := r.ExplainMemoryLimit()
+	if want := (LimitSource{Origin: OriginCgroup, Path: "/", File: "memory.max"}); n != 536870912 || source != want {
+		t.Errorf("ExplainMemoryLimit() = %d, %+v, want %d, %+v", n, source, 536870912, want)
+	}
+}
+
+func TestLimitSource(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/memory.max":                "536870912\n",
+		"sys/fs/cgroup/app.slice/app/memory.max":            "max\n",
+		"sys/fs/cgroup/app.slice/app/cpu.max":               "400000 100000\n",
+		"sys/fs/cgroup/app.slice/app/cpuset.cpus.effective": "0-1\n",
+	})
+	n, source := r.ExplainMemoryLimit()
+	if want := (LimitSource{Origin: OriginAncestor, Path: "/app.slice", File: "memory.max"}); n != 536870912 || source != want {
+		t.Errorf("ExplainMemoryLimit() = %d, %+v, want %d, %+v", n, source, 536870912, want)
+	}
+	if s := source.String(); s != "ancestor /app.slice memory.max" {
+		t.Errorf("LimitSource.String() = %q", s)
+	}
+	q, source := r.ExplainCPUQuota()
+	if want := (LimitSource{Origin: OriginCPUSet, Path: "/app.slice/app", File: "cpuset.cpus.effective"}); q != 2 || source != want {
+		t.Errorf("ExplainCPUQuota() = %f, %+v, want %f, %+v", q, source, 2.0, want)
+	}
+
+	writeFiles(t, filepath.Dir(r.ProcRoot()), map[string]string{
+		"sys/fs/cgroup/app.slice/app/cpu.max": "150000 100000\n",
+	})
+	q, source = r.ExplainCPUQuota()
+	if want := (LimitSource{Origin: OriginCgroup, Path: "/app.slice/app", File: "cpu.max"}); q != 1.5 || source != want {
+		t.Errorf("ExplainCPUQuota() = %f, %+v, want %f, %+v", q, source, 1.5, want)
+	}
+
+	// the tighter quota of the parent slice applies
+	writeFiles(t, filepath.Dir(r.ProcRoot()), map[string]string{
+		"sys/fs/cgroup/app.slice/cpu.max": "50000 100000\n",
+	})
+	q, source = r.ExplainCPUQuota()
+	if want := (LimitSource{Origin: OriginAncestor, Path: "/app.slice", File: "cpu.max"}); q != 0.5 || source != want {
+		t.Errorf("ExplainCPUQuota() = %f, %+v, want %f, %+v", q, source, 0.5, want)
+	}
+	if l := r.GetLimits(); l.CPUQuota != 0.5 {
+		t.Errorf("GetLimits().CPUQuota = %f, want %f", l.CPUQuota, 0.5)
+	}
+}
+
+func TestExplainCPUQuotaWithoutController(t *testing.T) {
+	// the cpu controller isn't enabled for the cgroup, there is no cpu.max
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/cpu.max": "300000 100000\n",
+		"sys/devices/system/cpu/online":   "0-7\n",
+	})
+	q, source := r.ExplainCPUQuota()
+	if want := (LimitSource{Origin: OriginAncestor, Path: "/app.slice", File: "cpu.max"}); q != 3 || source != want {
+		t.Errorf("ExplainCPUQuota() = %f, %+v, want %f, %+v", q, source, 3.0, want)
+	}
+
+	r = newUnifiedReader(t, map[string]string{
+		"sys/devices/system/cpu/online": "0-7\n",
+	})
+	q, source = r.ExplainCPUQuota()
+	if q != 8 || source.Origin != OriginHost {
+		t.Errorf("ExplainCPUQuota() = %f, %+v, want %f of the host", q, source, 8.0)
+	}
+
+	r = newUnifiedReader(t, nil)
+	if q, source = r.ExplainCPUQuota(); q <= 0 || source.Origin != OriginHost {
+		t.Errorf("ExplainCPUQuota() = %f, %+v, want the CPUs of the host", q, source)
+	}
+}
+
+func TestForPIDAndOpen(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"proc/42/cgroup": "0::/worker.slice/w1\n",
+		"sys/fs/cgroup/worker.slice/w1/memory.max":  "max\n",
+		"sys/fs/cgroup/worker.slice/w2/memory.max":  "2097152\n",
+		"sys/fs/cgroup/app.slice/app/memory.max":    "1048576\n",
+		"sys/fs/cgroup/worker.slice/w2/cpuset.cpus": "0-1\n",
+	})
+
+	c, err := r.ForPID(42)
+	if err != nil {
+		t.Fatalf("ForPID() error: %v", err)
+	}
+	if p := c.CgroupPath(); p != "/worker.slice/w1" {
+		t.Errorf("CgroupPath() = %q, want %q", p, "/worker.slice/w1")
+	}
+	if n := c.GetMemoryLimit(); n != math.MaxInt64 {
+		t.Errorf("GetMemoryLimit() = %d, want %d", n, int64(math.MaxInt64))
+	}
+
+	c, err = r.Open("/worker.slice/w2")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if n := c.GetMemoryLimit(); n != 2097152 {
+		t.Errorf("GetMemoryLimit() = %d, want %d", n, 2097152)
+	}
+	if n := r.GetMemoryLimit(); n != 1048576 {
+		t.Errorf("GetMemoryLimit() of self = %d, want %d", n, 1048576)
+	}
+
+	if _, err = r.Open("/worker.slice/w3"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open() of a missing cgroup error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestNewCPUUsage(t *testing.T) {
+	prev := &CPUStat{UsageUsec: 1000000, NrPeriods: 100, NrThrottled: 10, ThrottledUsec: 50000}
+	cur := &CPUStat{UsageUsec: 2500000, NrPeriods: 110, NrThrottled: 15, ThrottledUsec: 250000}
+
+	u := newCPUUsage(prev, cur, time.Second, 2)
+	want := CPUUsage{Interval: time.Second, Cores: 1.5, Quota: 2, QuotaPercent: 75, NrPeriods: 10, NrThrottled: 5, ThrottledRatio: 0.2}
+	if *u != want {
+		t.Errorf("newCPUUsage() = %+v, want %+v", *u, want)
+	}
+}
+
+func TestCPUSamplerConcurrent(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/cpu.stat": "usage_usec 0\nnr_periods 0\n",
+	})
+	statFile := filepath.Join(r.SysRoot(), "fs/cgroup/app.slice/app/cpu.stat")
+	s, err := r.NewCPUSampler()
+	if err != nil {
+		t.Fatalf("NewCPUSampler() error: %v", err)
+	}
+
+	// the counters increase monotonically, they are replaced atomically
+	stop := make(chan struct{})
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		for i := 1; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			tmp := statFile + ".tmp"
+			_ = os.WriteFile(tmp, []byte(fmt.Sprintf("usage_usec %d\nnr_periods %d\n", i*1000, i)), 0o644)
+			_ = os.Rename(tmp, statFile)
+		}
+	}()
+
+	var samplers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		samplers.Add(1)
+		go func() {
+			defer samplers.Done()
+			for j := 0; j < 50; j++ {
+				u, err := s.Sample()
+				if err != nil {
+					t.Errorf("Sample() error: %v", err)
+					return
+				}
+				if u.NrPeriods < 0 || u.Cores < 0 {
+					t.Errorf("Sample() = %+v, want non-negative deltas", *u)
+					return
+				}
+			}
+		}()
+	}
+	samplers.Wait()
+	close(stop)
+	writer.Wait()
+}
+
+func TestMemoryEvents(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/memory.events":       "low 0\nhigh 12\nmax 3\noom 1\noom_kill 1\noom_group_kill 0\n",
+		"sys/fs/cgroup/app.slice/app/memory.events.local": "low 0\nhigh 2\nmax 0\noom 0\noom_kill 0\noom_group_kill 0\n",
+	})
+
+	events, err := r.GetMemoryEvents()
+	if err != nil {
+		t.Fatalf("GetMemoryEvents() error: %v", err)
+	}
+	if want := (MemoryEvents{High: 12, Max: 3, OOM: 1, OOMKill: 1}); *events != want {
+		t.Errorf("GetMemoryEvents() = %+v, want %+v", *events, want)
+	}
+	local, err := r.GetMemoryEventsLocal()
+	if err != nil {
+		t.Fatalf("GetMemoryEventsLocal() error: %v", err)
+	}
+	if want := (MemoryEvents{High: 2}); *local != want {
+		t.Errorf("GetMemoryEventsLocal() = %+v, want %+v", *local, want)
+	}
+
+	diff := diffMemoryEvents(local, events)
+	want := []MemoryEvent{
+		{Type: MemoryEventHigh, Count: 10, Events: *events},
+		{Type: MemoryEventMax, Count: 3, Events: *events},
+		{Type: MemoryEventOOM, Count: 1, Events: *events},
+		{Type: MemoryEventOOMKill, Count: 1, Events: *events},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("diffMemoryEvents() = %+v, want %+v", diff, want)
+	}
+}
+
+func TestMemoryEventsV1(t *testing.T) {
+	r := newV1Reader(t, false, map[string]string{
+		"sys/fs/cgroup/memory/app/memory.oom_control": "oom_kill_disable 1\nunder_oom 1\noom_kill 3\n",
+	})
+	events, err := r.GetMemoryEvents()
+	if err != nil {
+		t.Fatalf("GetMemoryEvents() error: %v", err)
+	}
+	if want := (MemoryEvents{OOMKill: 3, OOMKillDisable: true, UnderOOM: true}); *events != want {
+		t.Errorf("GetMemoryEvents() = %+v, want %+v", *events, want)
+	}
+	if _, err := r.GetMemoryEventsLocal(); err == nil {
+		t.Errorf("GetMemoryEventsLocal() should fail in %s mode", r.Mode())
+	}
+
+	buf := make([]byte, 8)
+	*(*uint64)(unsafe.Pointer(&buf[0])) = 2
+	if n, err := parseEventfdCount(buf); err != nil || n != 2 {
+		t.Errorf("parseEventfdCount() = %d, %v, want %d", n, err, 2)
+	}
+	if _, err := parseEventfdCount(buf[:4]); err == nil {
+		t.Errorf("parseEventfdCount() of a short read should fail")
+	}
+}
+
+func TestWatchMemoryEvents(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/memory.events": "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch, err := r.WatchMemoryEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchMemoryEvents() error: %v", err)
+	}
+
+	// inotify reports the writes of the fixture file like the kernel's notifications of memory.events
+	writeFiles(t, filepath.Join(r.SysRoot(), ".."), map[string]string{
+		"sys/fs/cgroup/app.slice/app/memory.events": "low 0\nhigh 0\nmax 1\noom 1\noom_kill 1\n",
+	})
+	var got []MemoryEventType
+	for e := range ch {
+		got = append(got, e.Type)
+		if len(got) == 3 {
+			cancel()
+		}
+	}
+	if want := []MemoryEventType{MemoryEventMax, MemoryEventOOM, MemoryEventOOMKill}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchMemoryEvents() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure("some avg10=1.50 avg60=0.25 avg300=0.00 total=123456\nfull avg10=0.10 avg60=0.00 avg300=0.00 total=789\n")
+	if err != nil {
+		t.Fatalf("parsePressure() error: %v", err)
+	}
+	want := Pressure{
+		Some: PressureValues{Avg10: 1.5, Avg60: 0.25, Total: 123456},
+		Full: PressureValues{Avg10: 0.1, Total: 789},
+	}
+	if *p != want {
+		t.Errorf("parsePressure() = %+v, want %+v", *p, want)
+	}
+
+	trigger := PressureTrigger{Resource: PressureMemory, Stall: 150 * time.Millisecond, Window: time.Second}
+	if s := trigger.String(); s != "some 150000 1000000" {
+		t.Errorf("PressureTrigger.String() = %q, want %q", s, "some 150000 1000000")
+	}
+	if err = trigger.validate(); err != nil {
+		t.Errorf("PressureTrigger.validate() error: %v", err)
+	}
+	trigger.Stall = 2 * time.Second
+	if err = trigger.validate(); err == nil {
+		t.Errorf("PressureTrigger.validate() should fail if stall > window")
+	}
+}
+
+func TestWatchSystemPressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := WatchSystemPressure(ctx, PressureTrigger{Resource: PressureMemory, Stall: 100 * time.Millisecond, Window: 2 * time.Second})
+	if err != nil {
+		t.Skipf("PSI triggers are not available: %v", err)
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestIOStat(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/io.stat": "8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n" +
+			"8:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=1252 dbytes=50331648 dios=3021\n",
+		"sys/fs/cgroup/app.slice/app/io.max": "8:16 rbps=2097152 wbps=max riops=max wiops=120\n",
+		"sys/dev/block/8:0/uevent":           "MAJOR=8\nMINOR=0\nDEVNAME=sda\nDEVTYPE=disk\n",
+		"sys/dev/block/8:16/uevent":          "MAJOR=8\nMINOR=16\nDEVNAME=sdb\nDEVTYPE=disk\n",
+	})
+
+	stats, err := r.GetIOStat()
+	if err != nil {
+		t.Fatalf("GetIOStat() error: %v", err)
+	}
+	wantStats := []IOStat{
+		{Major: 8, Minor: 0, Device: "sda", RBytes: 90430464, WBytes: 299008000, RIOs: 8950, WIOs: 1252, DBytes: 50331648, DIOs: 3021},
+		{Major: 8, Minor: 16, Device: "sdb", RBytes: 1459200, WBytes: 314773504, RIOs: 192, WIOs: 353},
+	}
+	if !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("GetIOStat() = %+v, want %+v", stats, wantStats)
+	}
+
+	limits, err := r.GetIOLimits()
+	if err != nil {
+		t.Fatalf("GetIOLimits() error: %v", err)
+	}
+	wantLimits := []IOLimit{{Major: 8, Minor: 16, Device: "sdb", RBps: 2097152, WBps: Unlimited, RIOPS: Unlimited, WIOPS: 120}}
+	if !reflect.DeepEqual(limits, wantLimits) {
+		t.Errorf("GetIOLimits() = %+v, want %+v", limits, wantLimits)
+	}
+}
+
+func TestIOStatV1(t *testing.T) {
+	r := newV1Reader(t, false, map[string]string{
+		"sys/fs/cgroup/blkio/app/blkio.throttle.io_service_bytes": "8:0 Read 90430464\n8:0 Write 299008000\n8:0 Sync 1000\n" +
+			"8:0 Async 2000\n8:0 Discard 50331648\n8:0 Total 389438464\n8:16 Read 1459200\n8:16 Write 314773504\nTotal 706671168\n",
+		"sys/fs/cgroup/blkio/app/blkio.throttle.io_serviced":       "8:0 Read 8950\n8:0 Write 1252\n8:0 Discard 3021\n8:16 Read 192\n8:16 Write 353\nTotal 13768\n",
+		"sys/fs/cgroup/blkio/app/blkio.throttle.read_bps_device":   "8:16 2097152\n",
+		"sys/fs/cgroup/blkio/app/blkio.throttle.write_bps_device":  "",
+		"sys/fs/cgroup/blkio/app/blkio.throttle.read_iops_device":  "",
+		"sys/fs/cgroup/blkio/app/blkio.throttle.write_iops_device": "8:16 120\n",
+		"sys/dev/block/8:0/uevent":                                 "MAJOR=8\nMINOR=0\nDEVNAME=sda\nDEVTYPE=disk\n",
+		"sys/dev/block/8:16/uevent":                                "MAJOR=8\nMINOR=16\nDEVNAME=sdb\nDEVTYPE=disk\n",
+	})
+
+	stats, err := r.GetIOStat()
+	if err != nil {
+		t.Fatalf("GetIOStat() error: %v", err)
+	}
+	wantStats := []IOStat{
+		{Major: 8, Minor: 0, Device: "sda", RBytes: 90430464, WBytes: 299008000, RIOs: 8950, WIOs: 1252, DBytes: 50331648, DIOs: 3021},
+		{Major: 8, Minor: 16, Device: "sdb", RBytes: 1459200, WBytes: 314773504, RIOs: 192, WIOs: 353},
+	}
+	if !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("GetIOStat() = %+v, want %+v", stats, wantStats)
+	}
+
+	limits, err := r.GetIOLimits()
+	if err != nil {
+		t.Fatalf("GetIOLimits() error: %v", err)
+	}
+	wantLimits := []IOLimit{{Major: 8, Minor: 16, Device: "sdb", RBps: 2097152, WBps: Unlimited, RIOPS: Unlimited, WIOPS: 120}}
+	if !reflect.DeepEqual(limits, wantLimits) {
+		t.Errorf("GetIOLimits() = %+v, want %+v", limits, wantLimits)
+	}
+}
+
+func TestHugeTLB(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages":    "0\n",
+		"sys/kernel/mm/hugepages/hugepages-1048576kB/nr_hugepages": "0\n",
+		"sys/fs/cgroup/app.slice/app/hugetlb.2MB.max":              "4194304\n",
+		"sys/fs/cgroup/app.slice/app/hugetlb.2MB.current":          "2097152\n",
+		"sys/fs/cgroup/app.slice/app/hugetlb.2MB.events":           "max 3\n",
+		"sys/fs/cgroup/app.slice/app/hugetlb.1GB.max":              "max\n",
+		"sys/fs/cgroup/app.slice/app/hugetlb.1GB.current":          "0\n",
+	})
+	stats, err := r.GetHugeTLBStats()
+	if err != nil {
+		t.Fatalf("GetHugeTLBStats() error: %v", err)
+	}
+	want := []HugeTLBStat{
+		{PageSize: 2 << 20, Name: "2MB", Limit: 4194304, Usage: 2097152, Failcnt: 3},
+		{PageSize: 1 << 30, Name: "1GB", Limit: math.MaxInt64},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("GetHugeTLBStats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestPids(t *testing.T) {
+	r := newUnifiedReader(t, map[string]string{
+		"sys/fs/cgroup/app.slice/app/pids.max":     "max\n",
+		"sys/fs/cgroup/app.slice/app/pids.current": "12\n",
+		"sys/fs/cgroup/app.slice/app/pids.events":  "max 3\n",
+	})
+
+	if n := r.GetPidsLimit(); n != math.MaxInt64 {
+		t.Errorf("GetPidsLimit() = %d, want %d", n, int64(math.MaxInt64))
+	}
+	if n := r.GetPidsCurrent(); n != 12 {
+		t.Errorf("GetPidsCurrent() = %d, want %d", n, 12)
+	}
+	events, err := r.GetPidsEvents()
+	if err != nil {
+		t.Fatalf("GetPidsEvents() error: %v", err)
+	}
+	if events.Max != 3 {
+		t.Errorf("GetPidsEvents().Max = %d, want %d", events.Max, 3)
+	}
+}
@@ -0,0 +1,266 @@
+package cgroup
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ContainerRuntime is the runtime of the container which the current process runs in.
+type ContainerRuntime string
+
+const (
+	RuntimeNone       ContainerRuntime = ""
+	RuntimeDocker     ContainerRuntime = "docker"
+	RuntimeContainerd ContainerRuntime = "containerd"
+	RuntimeCRIO       ContainerRuntime = "cri-o"
+	RuntimePodman     ContainerRuntime = "podman"
+	RuntimeLXC        ContainerRuntime = "lxc"
+	RuntimeNspawn     ContainerRuntime = "systemd-nspawn"
+	RuntimeWSL        ContainerRuntime = "wsl"
+	RuntimeECS        ContainerRuntime = "ecs"
+	// RuntimeUnknown is a container of an unknown runtime, eg: the "container" environment var of pid 1 is "oci".
+	RuntimeUnknown ContainerRuntime = "unknown"
+)
+
+// ContainerInfo is the container which the current process runs in.
+type ContainerInfo struct {
+	// Runtime is the container runtime, RuntimeNone if the process doesn't run in a container.
+	Runtime ContainerRuntime `json:"runtime" yaml:"runtime" mapstructure:"runtime"`
+	// ID is the container ID, eg: the 64 hex digits of docker, or the container name of LXC. It is empty if unknown.
+	ID string `json:"id" yaml:"id" mapstructure:"id"`
+	// PodUID is the UID of the kubernetes pod, it is empty if the container is not in a pod.
+	PodUID string `json:"pod_uid" yaml:"pod_uid" mapstructure:"pod_uid"`
+}
+
+// InContainer reports whether the process runs in a container.
+func (i ContainerInfo) InContainer() bool {
+	return i.Runtime != RuntimeNone
+}
+
+// DetectContainer detects the container of the current process, see Reader.DetectContainer.
+func DetectContainer() ContainerInfo {
+	return defaultReader.DetectContainer()
+}
+
+// RunInContainer returns true if the current process runs in a container of any runtime, see DetectContainer.
+func RunInContainer() bool {
+	return DetectContainer().InContainer()
+}
+
+var (
+	containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+	// the systemd cgroup driver replaces "-" of the pod UID with "_", eg: kubepods-besteffort-pod1a2b_....slice
+	podUIDPattern     = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+	kubeletPodPattern = regexp.MustCompile(`/kubelet/pods/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})/`)
+)
+
+// cgroupRuntimeMarkers are the cgroup path patterns of the runtimes, in the order of the precedence.
+var cgroupRuntimeMarkers = []struct {
+	marker  string
+	runtime ContainerRuntime
+}{
+	{"cri-containerd-", RuntimeContainerd},
+	{"crio-", RuntimeCRIO},
+	{"libpod-", RuntimePodman},
+	{"/libpod_parent/", RuntimePodman},
+	{"docker-", RuntimeDocker},
+	{"/docker/", RuntimeDocker},
+	{"/ecs/", RuntimeECS},
+	{"lxc.payload.", RuntimeLXC},
+	{"/lxc/", RuntimeLXC},
+}
+
+// mountRuntimeMarkers are the mount roots of /etc/hostname, /etc/hosts and /etc/resolv.conf bind-mounted by the
+// runtimes, which are visible even if the cgroup namespace hides the cgroup path.
+var mountRuntimeMarkers = []struct {
+	marker  string
+	runtime ContainerRuntime
+}{
+	{"/docker/containers/", RuntimeDocker},
+	{"/io.containerd.", RuntimeContainerd},
+	{"/containers/storage/overlay-containers/", RuntimePodman},
+}
+
+// environRuntimes are the values of the "container" environment var of pid 1.
+var environRuntimes = map[string]ContainerRuntime{
+	"docker":         RuntimeDocker,
+	"podman":         RuntimePodman,
+	"crio":           RuntimeCRIO,
+	"lxc":            RuntimeLXC,
+	"lxc-libvirt":    RuntimeLXC,
+	"systemd-nspawn": RuntimeNspawn,
+}
+
+// DetectContainer detects the container runtime, the container ID and the kubernetes pod UID of the current process.
+//
+// It looks at, in order:
+//   - the cgroup paths in /proc/self/cgroup, eg: /docker/<id>, cri-containerd-<id>.scope, crio-<id>.scope,
+//     libpod-<id>.scope, /ecs/<task>/<id>, lxc.payload.<name> and the pod<uid> of kubepods
+//   - the bind mounts of /etc/hostname, /etc/hosts and /etc/resolv.conf in /proc/self/mountinfo
+//   - the marker files /run/.containerenv of podman and CRI-O, /.dockerenv of docker
+//   - the ECS_CONTAINER_METADATA_URI environment var of ECS in /proc/1/environ
+//   - the "container" environment var of /proc/1/environ, set by podman, LXC and systemd-nspawn
+//   - "microsoft" in /proc/sys/kernel/osrelease of WSL
+//
+// The marker files are looked up in the parent of procRoot, which is "/" for the default reader.
+func (r *Reader) DetectContainer() ContainerInfo {
+	var info ContainerInfo
+	rootDir := path.Dir(r.procRoot)
+
+	if data, err := os.ReadFile(path.Join(r.procRoot, "self/cgroup")); err == nil {
+		info = detectContainerFromCgroup(string(data))
+	}
+	if data, err := os.ReadFile(path.Join(r.procRoot, "self/mountinfo")); err == nil {
+		mountInfo := detectContainerFromMountInfo(string(data))
+		if info.Runtime == RuntimeNone {
+			info.Runtime = mountInfo.Runtime
+		}
+		if info.ID == "" {
+			info.ID = mountInfo.ID
+		}
+		if info.PodUID == "" {
+			info.PodUID = mountInfo.PodUID
+		}
+	}
+	if info.Runtime != RuntimeNone {
+		return info
+	}
+
+	switch {
+	case fileExists(path.Join(rootDir, "run/.containerenv")):
+		info.Runtime = RuntimePodman
+		if info.PodUID != "" {
+			// CRI-O creates /run/.containerenv as well
+			info.Runtime = RuntimeCRIO
+		}
+	case fileExists(path.Join(rootDir, ".dockerenv")):
+		info.Runtime = RuntimeDocker
+	}
+	if info.Runtime != RuntimeNone {
+		return info
+	}
+
+	// the environ of pid 1 is only readable by the same user
+	if data, err := os.ReadFile(path.Join(r.procRoot, "1/environ")); err == nil {
+		var containerRuntime ContainerRuntime
+		for _, env := range strings.Split(string(data), "\x00") {
+			name, value, _ := strings.Cut(env, "=")
+			switch {
+			case (name == "ECS_CONTAINER_METADATA_URI_V4" || name == "ECS_CONTAINER_METADATA_URI") && value != "":
+				info.Runtime = RuntimeECS
+				return info
+			case name == "container" && containerRuntime == RuntimeNone:
+				containerRuntime = RuntimeUnknown
+				if runtime, ok := environRuntimes[value]; ok {
+					containerRuntime = runtime
+				}
+			}
+		}
+		if containerRuntime != RuntimeNone {
+			info.Runtime = containerRuntime
+			return info
+		}
+	}
+	if data, err := os.ReadFile(path.Join(r.procRoot, "sys/kernel/osrelease")); err == nil &&
+		strings.Contains(strings.ToLower(string(data)), "microsoft") {
+		info.Runtime = RuntimeWSL
+		return info
+	}
+	if info.ID != "" || info.PodUID != "" {
+		info.Runtime = RuntimeUnknown
+	}
+	return info
+}
+
+// detectContainerFromCgroup detects the container from the content of /proc/self/cgroup.
+func detectContainerFromCgroup(data string) ContainerInfo {
+	var info ContainerInfo
+	paths := parseProcCgroup(data)
+	// the map is iterated in order for a stable result
+	controllers := make([]string, 0, len(paths))
+	for controller := range paths {
+		controllers = append(controllers, controller)
+	}
+	sort.Strings(controllers)
+
+	for _, controller := range controllers {
+		p := paths[controller]
+		if info.PodUID == "" {
+			if m := podUIDPattern.FindStringSubmatch(p); m != nil {
+				info.PodUID = strings.ReplaceAll(m[1], "_", "-")
+			}
+		}
+		if info.ID == "" {
+			if ids := containerIDPattern.FindAllString(p, -1); len(ids) > 0 {
+				info.ID = ids[len(ids)-1]
+			}
+		}
+		if info.Runtime != RuntimeNone {
+			continue
+		}
+		for _, m := range cgroupRuntimeMarkers {
+			i := strings.Index(p, m.marker)
+			if i < 0 {
+				continue
+			}
+			info.Runtime = m.runtime
+			if m.runtime == RuntimeLXC && info.ID == "" {
+				// the container name, eg: /lxc.payload.web/... or /lxc/web
+				name := p[i+len(m.marker):]
+				name, _, _ = strings.Cut(name, "/")
+				info.ID = name
+			}
+			break
+		}
+	}
+	if info.Runtime == RuntimeNone && info.PodUID != "" {
+		// the cgroupfs driver of the kubelet has no runtime marker, eg: /kubepods/burstable/pod<uid>/<id>
+		info.Runtime = RuntimeUnknown
+	}
+	return info
+}
+
+// detectContainerFromMountInfo detects the container from the bind mounts in /proc/self/mountinfo.
+func detectContainerFromMountInfo(data string) ContainerInfo {
+	var info ContainerInfo
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		switch unescapeMountPath(fields[4]) {
+		case "/etc/hostname", "/etc/hosts", "/etc/resolv.conf":
+		default:
+			continue
+		}
+		root := unescapeMountPath(fields[3])
+		if m := kubeletPodPattern.FindStringSubmatch(root); m != nil && info.PodUID == "" {
+			info.PodUID = m[1]
+		}
+		for _, m := range mountRuntimeMarkers {
+			if !strings.Contains(root, m.marker) {
+				continue
+			}
+			if info.Runtime == RuntimeNone {
+				info.Runtime = m.runtime
+			}
+			// containerd mounts the files of the pod sandbox, whose ID is not the container ID
+			if id := containerIDPattern.FindString(root); id != "" && info.ID == "" && m.runtime != RuntimeContainerd {
+				info.ID = id
+			}
+		}
+	}
+	if info.Runtime == RuntimePodman && info.PodUID != "" {
+		// CRI-O shares the containers/storage with podman
+		info.Runtime = RuntimeCRIO
+	}
+	return info
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -3,68 +3,195 @@ package cgroup
 import (
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 )
 
+// GetCPUQuota returns the number of CPU cores available to the current process from the CFS quota,
+// or the number of online CPUs if the quota isn't set. It is capped by the number of CPUs in the cpuset.
 func GetCPUQuota() float64 {
-	cpuQuota, err := getCPUQuotaGeneric()
+	return defaultReader.GetCPUQuota()
+}
+
+// GetCPUQuota returns the number of CPU cores available from the CFS quota, see GetCPUQuota.
+func (c *Cgroup) GetCPUQuota() float64 {
+	cpuQuota, _ := c.ExplainCPUQuota()
+	return cpuQuota
+}
+
+// CPUWeight is the relative CPU weight of a cgroup, normalised to both the cgroup v1 and v2 scales.
+type CPUWeight struct {
+	// Weight is the cgroup v2 cpu.weight in [1, 10000], the default is 100.
+	Weight int64 `json:"weight" yaml:"weight" mapstructure:"weight"`
+	// Shares is the cgroup v1 cpu.shares in [2, 262144], the default is 1024.
+	Shares int64 `json:"shares" yaml:"shares" mapstructure:"shares"`
+	// Nice is the cgroup v2 cpu.weight.nice in [-20, 19], it is 0 in cgroup v1.
+	Nice int64 `json:"nice" yaml:"nice" mapstructure:"nice"`
+}
+
+// Cores returns the number of CPU cores requested, kubernetes sets the shares to the CPU request * 1024.
+func (w CPUWeight) Cores() float64 {
+	return float64(w.Shares) / 1024
+}
+
+// GetCPUWeight returns the CPU weight of the current process from "cpu.weight" and "cpu.weight.nice" in cgroup v2,
+// or from "cpu.shares" in cgroup v1.
+func GetCPUWeight() (*CPUWeight, error) {
+	return defaultReader.GetCPUWeight()
+}
+
+// GetCPUWeight returns the CPU weight, see GetCPUWeight.
+func (c *Cgroup) GetCPUWeight() (*CPUWeight, error) {
+	if c.r.Mode() == ModeUnified {
+		weight, err := c.getStatGeneric("", "cpu.weight")
+		if err != nil {
+			return nil, err
+		}
+		// cpu.weight.nice is absent if the cpu controller is not enabled in the parent.
+		nice, _ := c.getStatGeneric("", "cpu.weight.nice")
+		return &CPUWeight{Weight: weight, Shares: CPUWeightToShares(weight), Nice: nice}, nil
+	}
+
+	// See https://www.kernel.org/doc/Documentation/scheduler/sched-design-CFS.txt
+	shares, err := c.getCFSStat("cpu.shares")
 	if err != nil {
-		return 0
+		return nil, err
 	}
-	if cpuQuota <= 0 {
-		// The quota isn't set. This may be the case in multilevel containers.
-		// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/685#issuecomment-674423728
-		return getOnlineCPUCount()
+	return &CPUWeight{Weight: CPUSharesToWeight(shares), Shares: shares}, nil
+}
+
+// CPUSharesToWeight converts the cgroup v1 cpu.shares to the cgroup v2 cpu.weight, as systemd and runc do.
+func CPUSharesToWeight(shares int64) int64 {
+	if shares <= 0 {
+		return 0
 	}
-	return cpuQuota
+	return 1 + ((shares-2)*9999)/262142
 }
 
-// GetCPUSet returns cpuset.cpus value.
-func GetCPUSet() string {
-	// See https://www.kernel.org/doc/Documentation/cgroup-v1/cpusets.txt
-	data, err := getFileContents("cpuset.cpus", "/sys/fs/cgroup/cpuset", "/proc/self/cgroup", "cpuset")
-	if err != nil {
-		return ""
+// CPUWeightToShares converts the cgroup v2 cpu.weight to the cgroup v1 cpu.shares, the reverse of CPUSharesToWeight.
+func CPUWeightToShares(weight int64) int64 {
+	if weight <= 0 {
+		return 0
 	}
-	return strings.TrimSpace(string(data))
+	return 2 + ((weight-1)*262142)/9999
+}
+
+// CPUStat is the CPU usage and the CFS throttling statistics, the times are in microseconds.
+// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#cpu-interface-files
+type CPUStat struct {
+	// UsageUsec is the total CPU time consumed by the tasks.
+	UsageUsec int64 `json:"usage_usec" yaml:"usage_usec" mapstructure:"usage_usec"`
+	// UserUsec is the CPU time consumed in user mode.
+	UserUsec int64 `json:"user_usec" yaml:"user_usec" mapstructure:"user_usec"`
+	// SystemUsec is the CPU time consumed in kernel mode.
+	SystemUsec int64 `json:"system_usec" yaml:"system_usec" mapstructure:"system_usec"`
+	// NrPeriods is the number of the elapsed enforcement periods.
+	NrPeriods int64 `json:"nr_periods" yaml:"nr_periods" mapstructure:"nr_periods"`
+	// NrThrottled is the number of the periods in which the tasks were throttled.
+	NrThrottled int64 `json:"nr_throttled" yaml:"nr_throttled" mapstructure:"nr_throttled"`
+	// ThrottledUsec is the total time the tasks were throttled.
+	ThrottledUsec int64 `json:"throttled_usec" yaml:"throttled_usec" mapstructure:"throttled_usec"`
+	// NrBursts is the number of the periods in which the burst happened.
+	NrBursts int64 `json:"nr_bursts" yaml:"nr_bursts" mapstructure:"nr_bursts"`
+	// BurstUsec is the total time the tasks ran over the quota with the burst.
+	BurstUsec int64 `json:"burst_usec" yaml:"burst_usec" mapstructure:"burst_usec"`
+}
+
+// GetCPUStat returns the CPU usage and throttling statistics of the current process, from "cpu.stat" in cgroup v2,
+// or from "cpu.stat" and "cpuacct.usage*" in cgroup v1.
+func GetCPUStat() (*CPUStat, error) {
+	return defaultReader.GetCPUStat()
 }
 
-func getCPUQuotaGeneric() (float64, error) {
-	quotaUS, err := getCPUStat("cpu.cfs_quota_us")
-	if err == nil {
-		periodUS, err := getCPUStat("cpu.cfs_period_us")
-		if err == nil {
-			return float64(quotaUS) / float64(periodUS), nil
+// GetCPUStat returns the CPU usage and throttling statistics, see GetCPUStat.
+func (c *Cgroup) GetCPUStat() (*CPUStat, error) {
+	if c.r.Mode() == ModeUnified {
+		m, err := c.getKeyValues("", "cpu.stat")
+		if err != nil {
+			return nil, err
 		}
+		return &CPUStat{
+			UsageUsec:     m["usage_usec"],
+			UserUsec:      m["user_usec"],
+			SystemUsec:    m["system_usec"],
+			NrPeriods:     m["nr_periods"],
+			NrThrottled:   m["nr_throttled"],
+			ThrottledUsec: m["throttled_usec"],
+			NrBursts:      m["nr_bursts"],
+			BurstUsec:     m["burst_usec"],
+		}, nil
+	}
+
+	// See https://www.kernel.org/doc/Documentation/scheduler/sched-bwc.txt
+	m, err := c.getKeyValues("cpu", "cpu.stat")
+	if err != nil {
+		return nil, err
+	}
+	// See https://www.kernel.org/doc/Documentation/cgroup-v1/cpuacct.txt
+	usage, err := c.getStatGeneric("cpuacct", "cpuacct.usage")
+	if err != nil {
+		return nil, err
+	}
+	stat := &CPUStat{
+		UsageUsec:     usage / 1000,
+		NrPeriods:     m["nr_periods"],
+		NrThrottled:   m["nr_throttled"],
+		ThrottledUsec: m["throttled_time"] / 1000,
+		NrBursts:      m["nr_bursts"],
+		BurstUsec:     m["burst_time"] / 1000,
 	}
-	return getCPUQuotaV2("/sys/fs/cgroup", "/proc/self/cgroup")
+	if user, err := c.getStatGeneric("cpuacct", "cpuacct.usage_user"); err == nil {
+		stat.UserUsec = user / 1000
+		stat.SystemUsec, _ = c.getStatGeneric("cpuacct", "cpuacct.usage_sys")
+		stat.SystemUsec /= 1000
+	} else if ticks, err := c.getKeyValues("cpuacct", "cpuacct.stat"); err == nil {
+		// the kernels before 4.6 only have cpuacct.stat in USER_HZ, which is 100 on most architectures.
+		stat.UserUsec = ticks["user"] * 1e6 / userHZ
+		stat.SystemUsec = ticks["system"] * 1e6 / userHZ
+	}
+	return stat, nil
 }
 
-func getCPUStat(statFileName string) (int64, error) {
-	n, err := getStatGeneric(statFileName, "/sys/fs/cgroup/cpu", "/proc/self/cgroup", "cpu,") // kubernetes
+// userHZ is the unit of the clock ticks reported to the userspace, see sysconf(_SC_CLK_TCK).
+const userHZ = 100
+
+func (c *Cgroup) getCPUQuotaGeneric() (float64, error) {
+	if c.r.Mode() == ModeUnified {
+		return c.getCPUQuotaV2()
+	}
+
+	quotaUS, err := c.getCFSStat("cpu.cfs_quota_us")
+	if err != nil {
+		return 0, err
+	}
+	periodUS, err := c.getCFSStat("cpu.cfs_period_us")
 	if err != nil {
-		n, err = getStatGeneric(statFileName, "/sys/fs/cgroup/cpu", "/proc/self/cgroup", "cpu/") // docker
+		return 0, err
 	}
+	return float64(quotaUS) / float64(periodUS), nil
+}
 
-	return n, err
+func (c *Cgroup) getCFSStat(statFileName string) (int64, error) {
+	// the "cpu" controller may be co-mounted with "cpuacct", eg: /sys/fs/cgroup/cpu,cpuacct
+	return c.getStatGeneric("cpu", statFileName)
 }
 
-func getOnlineCPUCount() float64 {
+func (c *Cgroup) getOnlineCPUCount() float64 {
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/685#issuecomment-674423728
-	data, err := os.ReadFile("/sys/devices/system/cpu/online")
+	data, err := os.ReadFile(path.Join(c.r.sysRoot, "devices/system/cpu/online"))
 	if err != nil {
 		return -1
 	}
-	n := float64(countCPUs(string(data)))
-	if n <= 0 {
+	cpus, err := ParseCPUSet(string(data))
+	if err != nil || cpus.Count() == 0 {
 		return -1
 	}
-	return n
+	return float64(cpus.Count())
 }
 
-func getCPUQuotaV2(sysPrefix, cgroupPath string) (float64, error) {
-	data, err := getFileContents("cpu.max", sysPrefix, cgroupPath, "")
+func (c *Cgroup) getCPUQuotaV2() (float64, error) {
+	data, err := c.getFileContents("", "cpu.max")
 	if err != nil {
 		return 0, err
 	}
@@ -95,31 +222,3 @@ func parseCPUMax(data string) (float64, error) {
 	}
 	return float64(quota) / float64(period), nil
 }
-
-func countCPUs(data string) int {
-	data = strings.TrimSpace(data)
-	n := 0
-	for _, s := range strings.Split(data, ",") {
-		n++
-		if !strings.Contains(s, "-") {
-			if _, err := strconv.Atoi(s); err != nil {
-				return -1
-			}
-			continue
-		}
-		bounds := strings.Split(s, "-")
-		if len(bounds) != 2 {
-			return -1
-		}
-		start, err := strconv.Atoi(bounds[0])
-		if err != nil {
-			return -1
-		}
-		end, err := strconv.Atoi(bounds[1])
-		if err != nil {
-			return -1
-		}
-		n += end - start
-	}
-	return n
-}
@@ -0,0 +1,147 @@
+package cgroup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CPUSet is a sorted list of the CPU or the memory node numbers, parsed from a list like "0-3,8,10-11".
+type CPUSet []int
+
+// maxCPUNumber bounds the CPU and the memory node numbers, it is far above the NR_CPUS of the kernel,
+// so a corrupt list such as "0-2000000000" fails instead of being expanded.
+const maxCPUNumber = 1 << 16
+
+// ParseCPUSet parses the list format of the cpuset files and /sys/devices/system/cpu/online,
+// the duplicated numbers are merged. An empty list returns an empty CPUSet.
+// The numbers must be less than 65536.
+// See https://man7.org/linux/man-pages/man7/cpuset.7.html
+func ParseCPUSet(data string) (CPUSet, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return CPUSet{}, nil
+	}
+	seen := map[int]bool{}
+	for _, s := range strings.Split(data, ",") {
+		start, end, found := strings.Cut(s, "-")
+		first, err := parseCPUNumber(start)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse cpuset %q: %w", data, err)
+		}
+		last := first
+		if found {
+			if last, err = parseCPUNumber(end); err != nil {
+				return nil, fmt.Errorf("cannot parse cpuset %q: %w", data, err)
+			}
+			if last < first {
+				return nil, fmt.Errorf("cannot parse cpuset %q: invalid range %q", data, s)
+			}
+		}
+		for i := first; i <= last; i++ {
+			seen[i] = true
+		}
+	}
+
+	cpus := make(CPUSet, 0, len(seen))
+	for i := range seen {
+		cpus = append(cpus, i)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+func parseCPUNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative number %d", n)
+	}
+	if n >= maxCPUNumber {
+		return 0, fmt.Errorf("number %d exceeds %d", n, maxCPUNumber-1)
+	}
+	return n, nil
+}
+
+// Count returns the number of the CPUs in the set.
+func (s CPUSet) Count() int {
+	return len(s)
+}
+
+// Contains reports whether cpu is in the set.
+func (s CPUSet) Contains(cpu int) bool {
+	i := sort.SearchInts(s, cpu)
+	return i < len(s) && s[i] == cpu
+}
+
+// String returns the set in the list format, eg: "0-3,8,10-11".
+func (s CPUSet) String() string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		j := i
+		for j+1 < len(s) && s[j+1] == s[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		if i == j {
+			b.WriteString(strconv.Itoa(s[i]))
+		} else {
+			fmt.Fprintf(&b, "%d-%d", s[i], s[j])
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// GetCPUSet returns the CPUs which the current process can run on, from "cpuset.cpus.effective" in cgroup v2,
+// or from "cpuset.effective_cpus" ("cpuset.cpus" before linux 4.x) in cgroup v1.
+// It returns nil if the cpuset is unavailable.
+func GetCPUSet() CPUSet {
+	return defaultReader.GetCPUSet()
+}
+
+// GetCPUSet returns the CPUs of the cpuset, see GetCPUSet.
+func (c *Cgroup) GetCPUSet() CPUSet {
+	return c.getCPUSet("cpuset.cpus.effective", "cpuset.effective_cpus", "cpuset.cpus")
+}
+
+// GetCPUSetMems returns the memory nodes which the current process can allocate on, from "cpuset.mems.effective"
+// in cgroup v2, or from "cpuset.effective_mems" ("cpuset.mems" before linux 4.x) in cgroup v1.
+// It returns nil if the cpuset is unavailable.
+func GetCPUSetMems() CPUSet {
+	return defaultReader.GetCPUSetMems()
+}
+
+// GetCPUSetMems returns the memory nodes of the cpuset, see GetCPUSetMems.
+func (c *Cgroup) GetCPUSetMems() CPUSet {
+	return c.getCPUSet("cpuset.mems.effective", "cpuset.effective_mems", "cpuset.mems")
+}
+
+func (c *Cgroup) getCPUSet(v2StatFileName string, v1StatFileNames ...string) CPUSet {
+	var data string
+	var err error
+	if c.r.Mode() == ModeUnified {
+		// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#cpuset-interface-files
+		data, err = c.getFileContents("", v2StatFileName)
+	} else {
+		// See https://www.kernel.org/doc/Documentation/cgroup-v1/cpusets.txt
+		for _, statFileName := range v1StatFileNames {
+			if data, err = c.getFileContents("cpuset", statFileName); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil
+	}
+	cpus, err := ParseCPUSet(data)
+	if err != nil {
+		return nil
+	}
+	return cpus
+}
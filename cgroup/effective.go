@@ -0,0 +1,119 @@
+package cgroup
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// EffectiveMemoryLimit returns the tightest memory limit of the current process, see Cgroup.EffectiveMemoryLimit.
+func EffectiveMemoryLimit() (int64, string) {
+	return defaultReader.EffectiveMemoryLimit()
+}
+
+// EffectiveMemoryLimit walks from the cgroup up to the root, and returns the tightest "memory.max"
+// ("memory.limit_in_bytes" in cgroup v1) and the path of the cgroup which sets it,
+// eg: a kubernetes pod-level cgroup above the container.
+//
+// It returns Unlimited and an empty path if there is no limit.
+func (c *Cgroup) EffectiveMemoryLimit() (int64, string) {
+	if c.r.Mode() == ModeUnified {
+		return c.effectiveLimit("", "memory.max")
+	}
+	return c.effectiveLimit("memory", "memory.limit_in_bytes")
+}
+
+// EffectiveMemoryHigh returns the tightest memory.high of the current process, see Cgroup.EffectiveMemoryHigh.
+func EffectiveMemoryHigh() (int64, string) {
+	return defaultReader.EffectiveMemoryHigh()
+}
+
+// EffectiveMemoryHigh walks from the cgroup up to the root, and returns the tightest "memory.high"
+// and the path of the cgroup which sets it. memory.high is only available in unified mode.
+//
+// It returns Unlimited and an empty path if there is no limit.
+func (c *Cgroup) EffectiveMemoryHigh() (int64, string) {
+	if c.r.Mode() != ModeUnified {
+		return Unlimited, ""
+	}
+	return c.effectiveLimit("", "memory.high")
+}
+
+// EffectiveCPUQuota returns the tightest CPU quota of the current process, see Cgroup.EffectiveCPUQuota.
+func EffectiveCPUQuota() (float64, string) {
+	return defaultReader.EffectiveCPUQuota()
+}
+
+// EffectiveCPUQuota walks from the cgroup up to the root, and returns the tightest CPU quota in cores
+// from "cpu.max" ("cpu.cfs_quota_us" / "cpu.cfs_period_us" in cgroup v1), and the path of the cgroup which sets it.
+//
+// It returns -1 and an empty path if there is no quota.
+func (c *Cgroup) EffectiveCPUQuota() (float64, string) {
+	unified := c.r.Mode() == ModeUnified
+	controller := "cpu"
+	if unified {
+		controller = ""
+	}
+	nodes, err := c.cgroupAncestors(controller)
+	if err != nil {
+		return -1, ""
+	}
+
+	quota, quotaPath := float64(-1), ""
+	for _, node := range nodes {
+		q, err := readCPUQuota(node.dir, unified)
+		if err != nil || q <= 0 {
+			continue
+		}
+		if quota < 0 || q < quota {
+			quota, quotaPath = q, node.path
+		}
+	}
+	return quota, quotaPath
+}
+
+// effectiveLimit returns the tightest value of statFileName from the cgroup up to the root, and the path of
+// the cgroup which sets it.
+func (c *Cgroup) effectiveLimit(controller, statFileName string) (int64, string) {
+	nodes, err := c.cgroupAncestors(controller)
+	if err != nil {
+		return Unlimited, ""
+	}
+
+	limit, limitPath := Unlimited, ""
+	for _, node := range nodes {
+		// the root cgroup has no limit file in cgroup v2
+		n, err := readStatFile(path.Join(node.dir, statFileName))
+		if err != nil || n >= v1MemoryUnlimited {
+			continue
+		}
+		if n < limit {
+			limit, limitPath = n, node.path
+		}
+	}
+	return limit, limitPath
+}
+
+// readCPUQuota reads the CPU quota in cores of the cgroup directory, -1 if there is no quota.
+func readCPUQuota(dir string, unified bool) (float64, error) {
+	if unified {
+		data, err := os.ReadFile(path.Join(dir, "cpu.max"))
+		if err != nil {
+			return 0, err
+		}
+		return parseCPUMax(strings.TrimSpace(string(data)))
+	}
+
+	quotaUS, err := readStatFile(path.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, err
+	}
+	if quotaUS <= 0 {
+		return -1, nil
+	}
+	periodUS, err := readStatFile(path.Join(dir, "cpu.cfs_period_us"))
+	if err != nil || periodUS <= 0 {
+		return 0, err
+	}
+	return float64(quotaUS) / float64(periodUS), nil
+}
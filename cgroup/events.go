@@ -0,0 +1,143 @@
+package cgroup
+
+import (
+	"context"
+	"fmt"
+)
+
+// MemoryEvents are the counters of the memory events, parsed from "memory.events" in cgroup v2,
+// or from "memory.oom_control" in cgroup v1.
+//
+// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
+type MemoryEvents struct {
+	// Low is the number of times the cgroup was reclaimed below memory.low, only in cgroup v2.
+	Low int64 `json:"low" yaml:"low" mapstructure:"low"`
+	// High is the number of times the cgroup was throttled and reclaimed over memory.high, only in cgroup v2.
+	High int64 `json:"high" yaml:"high" mapstructure:"high"`
+	// Max is the number of times the cgroup was about to go over memory.max, only in cgroup v2.
+	Max int64 `json:"max" yaml:"max" mapstructure:"max"`
+	// OOM is the number of times the cgroup hit the limit and the allocation failed, only in cgroup v2.
+	OOM int64 `json:"oom" yaml:"oom" mapstructure:"oom"`
+	// OOMKill is the number of processes killed by the OOM killer.
+	OOMKill int64 `json:"oom_kill" yaml:"oom_kill" mapstructure:"oom_kill"`
+	// OOMGroupKill is the number of times a group OOM happened, only in cgroup v2.
+	OOMGroupKill int64 `json:"oom_group_kill" yaml:"oom_group_kill" mapstructure:"oom_group_kill"`
+	// OOMKillDisable is true if the OOM killer is disabled, only in cgroup v1.
+	OOMKillDisable bool `json:"oom_kill_disable" yaml:"oom_kill_disable" mapstructure:"oom_kill_disable"`
+	// UnderOOM is true if the cgroup is under OOM and the tasks are stopped, only in cgroup v1.
+	UnderOOM bool `json:"under_oom" yaml:"under_oom" mapstructure:"under_oom"`
+}
+
+// GetMemoryEvents returns the memory events of the current process from "memory.events" in cgroup v2,
+// or from "memory.oom_control" in cgroup v1. The cgroup v2 counters include the events of the descendants.
+func GetMemoryEvents() (*MemoryEvents, error) {
+	return defaultReader.GetMemoryEvents()
+}
+
+// GetMemoryEvents returns the memory events, see GetMemoryEvents.
+func (c *Cgroup) GetMemoryEvents() (*MemoryEvents, error) {
+	if c.r.Mode() != ModeUnified {
+		// See https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt
+		m, err := c.getKeyValues("memory", "memory.oom_control")
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryEvents{
+			OOMKill:        m["oom_kill"],
+			OOMKillDisable: m["oom_kill_disable"] == 1,
+			UnderOOM:       m["under_oom"] == 1,
+		}, nil
+	}
+	return c.getMemoryEventsV2("memory.events")
+}
+
+// GetMemoryEventsLocal returns the memory events of the current process from "memory.events.local",
+// the counters only include the events of the cgroup itself, only available in unified mode.
+func GetMemoryEventsLocal() (*MemoryEvents, error) {
+	return defaultReader.GetMemoryEventsLocal()
+}
+
+// GetMemoryEventsLocal returns the memory events of the cgroup itself, see GetMemoryEventsLocal.
+func (c *Cgroup) GetMemoryEventsLocal() (*MemoryEvents, error) {
+	if m := c.r.Mode(); m != ModeUnified {
+		return nil, fmt.Errorf("memory.events.local is not available in %s mode", m)
+	}
+	return c.getMemoryEventsV2("memory.events.local")
+}
+
+func (c *Cgroup) getMemoryEventsV2(statFileName string) (*MemoryEvents, error) {
+	m, err := c.getKeyValues("", statFileName)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryEvents{
+		Low:          m["low"],
+		High:         m["high"],
+		Max:          m["max"],
+		OOM:          m["oom"],
+		OOMKill:      m["oom_kill"],
+		OOMGroupKill: m["oom_group_kill"],
+	}, nil
+}
+
+// MemoryEventType is the type of MemoryEvent.
+type MemoryEventType string
+
+const (
+	MemoryEventLow          MemoryEventType = "low"
+	MemoryEventHigh         MemoryEventType = "high"
+	MemoryEventMax          MemoryEventType = "max"
+	MemoryEventOOM          MemoryEventType = "oom"
+	MemoryEventOOMKill      MemoryEventType = "oom_kill"
+	MemoryEventOOMGroupKill MemoryEventType = "oom_group_kill"
+)
+
+// MemoryEvent is a notification of WatchMemoryEvents.
+type MemoryEvent struct {
+	// Type is the type of the event.
+	Type MemoryEventType `json:"type" yaml:"type" mapstructure:"type"`
+	// Count is the number of the events of Type since the previous notification.
+	Count int64 `json:"count" yaml:"count" mapstructure:"count"`
+	// Events are the counters after the event.
+	Events MemoryEvents `json:"events" yaml:"events" mapstructure:"events"`
+}
+
+// WatchMemoryEvents watches the memory events of the current process, see Cgroup.WatchMemoryEvents.
+func WatchMemoryEvents(ctx context.Context) (<-chan MemoryEvent, error) {
+	return defaultReader.WatchMemoryEvents(ctx)
+}
+
+// WatchMemoryEvents delivers the high/max/oom/oom_kill events of the cgroup on the returned channel,
+// the channel is closed when ctx is done or the watch fails.
+//
+// It watches "memory.events" with inotify in cgroup v2, and "memory.oom_control" with an eventfd
+// registered in "cgroup.event_control" in cgroup v1, which requires the write permission of the cgroup.
+// Only MemoryEventOOM and MemoryEventOOMKill are delivered in cgroup v1.
+func (c *Cgroup) WatchMemoryEvents(ctx context.Context) (<-chan MemoryEvent, error) {
+	if c.r.Mode() == ModeUnified {
+		return c.watchMemoryEventsV2(ctx)
+	}
+	return c.watchMemoryEventsV1(ctx)
+}
+
+// diffMemoryEvents returns the events happened between the counters prev and cur.
+func diffMemoryEvents(prev, cur *MemoryEvents) []MemoryEvent {
+	var events []MemoryEvent
+	for _, d := range []struct {
+		t    MemoryEventType
+		prev int64
+		cur  int64
+	}{
+		{MemoryEventLow, prev.Low, cur.Low},
+		{MemoryEventHigh, prev.High, cur.High},
+		{MemoryEventMax, prev.Max, cur.Max},
+		{MemoryEventOOM, prev.OOM, cur.OOM},
+		{MemoryEventOOMKill, prev.OOMKill, cur.OOMKill},
+		{MemoryEventOOMGroupKill, prev.OOMGroupKill, cur.OOMGroupKill},
+	} {
+		if d.cur > d.prev {
+			events = append(events, MemoryEvent{Type: d.t, Count: d.cur - d.prev, Events: *cur})
+		}
+	}
+	return events
+}
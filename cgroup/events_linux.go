@@ -0,0 +1,133 @@
+package cgroup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"unsafe"
+)
+
+func (c *Cgroup) watchMemoryEventsV2(ctx context.Context) (<-chan MemoryEvent, error) {
+	dir, err := c.cgroupDir("")
+	if err != nil {
+		return nil, err
+	}
+	prev, err := c.getMemoryEventsV2("memory.events")
+	if err != nil {
+		return nil, err
+	}
+
+	// the kernel generates a file modified event when memory.events changes
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("cannot init inotify: %w", err)
+	}
+	if _, err = syscall.InotifyAddWatch(fd, path.Join(dir, "memory.events"), syscall.IN_MODIFY); err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("cannot watch memory.events: %w", err)
+	}
+
+	ch := make(chan MemoryEvent, 16)
+	go watchFile(ctx, os.NewFile(uintptr(fd), "inotify"), ch, func([]byte) ([]MemoryEvent, error) {
+		cur, err := c.getMemoryEventsV2("memory.events")
+		if err != nil {
+			return nil, err
+		}
+		events := diffMemoryEvents(prev, cur)
+		prev = cur
+		return events, nil
+	})
+	return ch, nil
+}
+
+func (c *Cgroup) watchMemoryEventsV1(ctx context.Context) (<-chan MemoryEvent, error) {
+	dir, err := c.cgroupDir("memory")
+	if err != nil {
+		return nil, err
+	}
+	prev, err := c.GetMemoryEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	// See https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt "OOM Control"
+	oomControl, err := os.Open(path.Join(dir, "memory.oom_control"))
+	if err != nil {
+		return nil, err
+	}
+	defer oomControl.Close()
+	efd, _, errno := syscall.RawSyscall(syscall.SYS_EVENTFD2, 0, syscall.O_CLOEXEC|syscall.O_NONBLOCK, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("cannot create eventfd: %w", errno)
+	}
+	eventFile := os.NewFile(efd, "eventfd")
+	// the kernel holds the eventfd, and signals it when the cgroup is under OOM
+	control := fmt.Sprintf("%d %d", efd, oomControl.Fd())
+	if err = os.WriteFile(path.Join(dir, "cgroup.event_control"), []byte(control), 0); err != nil {
+		_ = eventFile.Close()
+		return nil, fmt.Errorf("cannot register the eventfd of memory.oom_control: %w", err)
+	}
+
+	ch := make(chan MemoryEvent, 16)
+	go watchFile(ctx, eventFile, ch, func(buf []byte) ([]MemoryEvent, error) {
+		// the eventfd counter is the number of the OOMs since the previous read
+		count, err := parseEventfdCount(buf)
+		if err != nil {
+			return nil, err
+		}
+		cur, err := c.GetMemoryEvents()
+		if err != nil {
+			return nil, err
+		}
+		events := []MemoryEvent{{Type: MemoryEventOOM, Count: int64(count), Events: *cur}}
+		events = append(events, diffMemoryEvents(prev, cur)...)
+		prev = cur
+		return events, nil
+	})
+	return ch, nil
+}
+
+// parseEventfdCount parses the counter read from an eventfd, an 8-byte integer in the host byte order.
+func parseEventfdCount(buf []byte) (uint64, error) {
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("unexpected eventfd read of %d bytes", len(buf))
+	}
+	return *(*uint64)(unsafe.Pointer(&buf[0])), nil
+}
+
+// watchFile reads f until ctx is done, poll is called with the bytes read to deliver the events into ch.
+// Both f and ch are closed when it returns.
+func watchFile(ctx context.Context, f *os.File, ch chan<- MemoryEvent, poll func(buf []byte) ([]MemoryEvent, error)) {
+	defer close(ch)
+	done := make(chan struct{})
+	defer close(done)
+	// closing f interrupts the blocking Read
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = f.Close()
+	}()
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			return
+		}
+		events, err := poll(buf[:n])
+		if err != nil {
+			return
+		}
+		for _, e := range events {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package cgroup
+
+import (
+	"context"
+	"errors"
+)
+
+func (c *Cgroup) watchMemoryEventsV2(ctx context.Context) (<-chan MemoryEvent, error) {
+	return nil, errors.New("memory events are only supported on linux")
+}
+
+func (c *Cgroup) watchMemoryEventsV1(ctx context.Context) (<-chan MemoryEvent, error) {
+	return nil, errors.New("memory events are only supported on linux")
+}
@@ -0,0 +1,117 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HugeTLBStat is the hugetlb usage and limit of a huge page size.
+type HugeTLBStat struct {
+	// PageSize is the huge page size in bytes.
+	PageSize int64 `json:"page_size" yaml:"page_size" mapstructure:"page_size"`
+	// Name is the page size in the file names of the hugetlb controller, eg: 2MB, 1GB.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+	// Limit is the limit of the huge pages in bytes, Unlimited if there is no limit.
+	Limit int64 `json:"limit" yaml:"limit" mapstructure:"limit"`
+	// Usage is the usage of the huge pages in bytes.
+	Usage int64 `json:"usage" yaml:"usage" mapstructure:"usage"`
+	// Failcnt is the number of the allocations failed because of Limit,
+	// from "hugetlb.<size>.failcnt" in cgroup v1 or the "max" of "hugetlb.<size>.events" in cgroup v2.
+	Failcnt int64 `json:"failcnt" yaml:"failcnt" mapstructure:"failcnt"`
+}
+
+// GetHugeTLBStats returns the hugetlb statistics of the current process, one per huge page size of the host,
+// from "hugetlb.<size>.max", "hugetlb.<size>.current" and "hugetlb.<size>.events" in cgroup v2,
+// or from "hugetlb.<size>.limit_in_bytes", "hugetlb.<size>.usage_in_bytes" and "hugetlb.<size>.failcnt" in cgroup v1.
+func GetHugeTLBStats() ([]HugeTLBStat, error) {
+	return defaultReader.GetHugeTLBStats()
+}
+
+// GetHugeTLBStats returns the hugetlb statistics per huge page size, see GetHugeTLBStats.
+func (c *Cgroup) GetHugeTLBStats() ([]HugeTLBStat, error) {
+	sizes, err := c.r.HugePageSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	unified := c.r.Mode() == ModeUnified
+	stats := make([]HugeTLBStat, 0, len(sizes))
+	for _, size := range sizes {
+		s := HugeTLBStat{PageSize: size, Name: hugePageSizeName(size)}
+		prefix := "hugetlb." + s.Name
+		if unified {
+			// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#hugetlb-interface-files
+			if s.Limit, err = c.getStatGeneric("", prefix+".max"); err != nil {
+				return nil, err
+			}
+			if s.Usage, err = c.getStatGeneric("", prefix+".current"); err != nil {
+				return nil, err
+			}
+			if m, err := c.getKeyValues("", prefix+".events"); err == nil {
+				s.Failcnt = m["max"]
+			}
+		} else {
+			// See https://www.kernel.org/doc/Documentation/cgroup-v1/hugetlb.txt
+			if s.Limit, err = c.getStatGeneric("hugetlb", prefix+".limit_in_bytes"); err != nil {
+				return nil, err
+			}
+			s.Limit = normalizeMemoryLimit(s.Limit)
+			if s.Usage, err = c.getStatGeneric("hugetlb", prefix+".usage_in_bytes"); err != nil {
+				return nil, err
+			}
+			s.Failcnt, _ = c.getStatGeneric("hugetlb", prefix+".failcnt")
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// HugePageSizes returns the huge page sizes in bytes supported by the host from "/sys/kernel/mm/hugepages",
+// in ascending order. It returns an empty list if huge pages are not supported.
+func HugePageSizes() ([]int64, error) {
+	return defaultReader.HugePageSizes()
+}
+
+// HugePageSizes returns the huge page sizes in bytes supported by the host, see HugePageSizes.
+func (r *Reader) HugePageSizes() ([]int64, error) {
+	entries, err := os.ReadDir(path.Join(r.sysRoot, "kernel/mm/hugepages"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []int64{}, nil
+		}
+		return nil, err
+	}
+	sizes := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		// eg: hugepages-2048kB
+		kb, found := strings.CutPrefix(entry.Name(), "hugepages-")
+		kb, suffixFound := strings.CutSuffix(kb, "kB")
+		if !found || !suffixFound {
+			continue
+		}
+		n, err := strconv.ParseInt(kb, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse huge page size %q: %w", entry.Name(), err)
+		}
+		sizes = append(sizes, n<<10)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	return sizes, nil
+}
+
+// hugePageSizeName returns the page size in the hugetlb file names, which is the same as the kernel's
+// mem_fmt, eg: 64KB, 2MB, 1GB.
+func hugePageSizeName(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%dGB", size>>30)
+	case size >= 1<<20:
+		return fmt.Sprintf("%dMB", size>>20)
+	default:
+		return fmt.Sprintf("%dKB", size>>10)
+	}
+}
@@ -0,0 +1,313 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IOStat is the I/O statistics of a block device.
+type IOStat struct {
+	Major int64 `json:"major" yaml:"major" mapstructure:"major"`
+	Minor int64 `json:"minor" yaml:"minor" mapstructure:"minor"`
+	// Device is the name of the device from /sys/dev/block, eg: sda. It is empty if the device is unknown.
+	Device string `json:"device" yaml:"device" mapstructure:"device"`
+	// RBytes is the number of bytes read.
+	RBytes int64 `json:"rbytes" yaml:"rbytes" mapstructure:"rbytes"`
+	// WBytes is the number of bytes written.
+	WBytes int64 `json:"wbytes" yaml:"wbytes" mapstructure:"wbytes"`
+	// RIOs is the number of read IOs.
+	RIOs int64 `json:"rios" yaml:"rios" mapstructure:"rios"`
+	// WIOs is the number of write IOs.
+	WIOs int64 `json:"wios" yaml:"wios" mapstructure:"wios"`
+	// DBytes is the number of bytes discarded.
+	DBytes int64 `json:"dbytes" yaml:"dbytes" mapstructure:"dbytes"`
+	// DIOs is the number of discard IOs.
+	DIOs int64 `json:"dios" yaml:"dios" mapstructure:"dios"`
+}
+
+// IOLimit is the I/O throttling limits of a block device, Unlimited means no limit.
+type IOLimit struct {
+	Major int64 `json:"major" yaml:"major" mapstructure:"major"`
+	Minor int64 `json:"minor" yaml:"minor" mapstructure:"minor"`
+	// Device is the name of the device from /sys/dev/block, eg: sda. It is empty if the device is unknown.
+	Device string `json:"device" yaml:"device" mapstructure:"device"`
+	// RBps is the limit of the read bytes per second.
+	RBps int64 `json:"rbps" yaml:"rbps" mapstructure:"rbps"`
+	// WBps is the limit of the written bytes per second.
+	WBps int64 `json:"wbps" yaml:"wbps" mapstructure:"wbps"`
+	// RIOPS is the limit of the read IOs per second.
+	RIOPS int64 `json:"riops" yaml:"riops" mapstructure:"riops"`
+	// WIOPS is the limit of the write IOs per second.
+	WIOPS int64 `json:"wiops" yaml:"wiops" mapstructure:"wiops"`
+}
+
+// GetIOStat returns the per-device I/O statistics of the current process from "io.stat" in cgroup v2,
+// or from "blkio.throttle.io_service_bytes" and "blkio.throttle.io_serviced" in cgroup v1.
+func GetIOStat() ([]IOStat, error) {
+	return defaultReader.GetIOStat()
+}
+
+// GetIOStat returns the per-device I/O statistics, see GetIOStat.
+func (c *Cgroup) GetIOStat() ([]IOStat, error) {
+	devices := map[string]*IOStat{}
+	device := func(key string) (*IOStat, error) {
+		if s, ok := devices[key]; ok {
+			return s, nil
+		}
+		major, minor, err := parseDeviceNumber(key)
+		if err != nil {
+			return nil, err
+		}
+		s := &IOStat{Major: major, Minor: minor, Device: c.r.blockDeviceName(key)}
+		devices[key] = s
+		return s, nil
+	}
+
+	if c.r.Mode() == ModeUnified {
+		// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#io-interface-files
+		//	8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+		data, err := c.getFileContents("", "io.stat")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			s, err := device(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			for key, n := range parseNestedKeys(fields[1:]) {
+				switch key {
+				case "rbytes":
+					s.RBytes = n
+				case "wbytes":
+					s.WBytes = n
+				case "rios":
+					s.RIOs = n
+				case "wios":
+					s.WIOs = n
+				case "dbytes":
+					s.DBytes = n
+				case "dios":
+					s.DIOs = n
+				}
+			}
+		}
+		return sortIOStats(devices), nil
+	}
+
+	// See https://www.kernel.org/doc/Documentation/cgroup-v1/blkio-controller.txt
+	//	8:0 Read 1459200
+	//	8:0 Write 314773504
+	//	Total 316232704
+	for _, file := range []string{"blkio.throttle.io_service_bytes", "blkio.throttle.io_serviced"} {
+		data, err := c.getFileContents("blkio", file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue
+			}
+			s, err := device(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			n, _ := strconv.ParseInt(fields[2], 10, 64)
+			bytes := file == "blkio.throttle.io_service_bytes"
+			switch {
+			case fields[1] == "Read" && bytes:
+				s.RBytes = n
+			case fields[1] == "Write" && bytes:
+				s.WBytes = n
+			case fields[1] == "Discard" && bytes:
+				s.DBytes = n
+			case fields[1] == "Read":
+				s.RIOs = n
+			case fields[1] == "Write":
+				s.WIOs = n
+			case fields[1] == "Discard":
+				s.DIOs = n
+			}
+		}
+	}
+	return sortIOStats(devices), nil
+}
+
+// GetIOLimits returns the per-device I/O limits of the current process from "io.max" in cgroup v2,
+// or from "blkio.throttle.*_device" in cgroup v1. The devices without any limit are not returned.
+func GetIOLimits() ([]IOLimit, error) {
+	return defaultReader.GetIOLimits()
+}
+
+// GetIOLimits returns the per-device I/O limits, see GetIOLimits.
+func (c *Cgroup) GetIOLimits() ([]IOLimit, error) {
+	devices := map[string]*IOLimit{}
+	device := func(key string) (*IOLimit, error) {
+		if l, ok := devices[key]; ok {
+			return l, nil
+		}
+		major, minor, err := parseDeviceNumber(key)
+		if err != nil {
+			return nil, err
+		}
+		l := &IOLimit{
+			Major:  major,
+			Minor:  minor,
+			Device: c.r.blockDeviceName(key),
+			RBps:   Unlimited,
+			WBps:   Unlimited,
+			RIOPS:  Unlimited,
+			WIOPS:  Unlimited,
+		}
+		devices[key] = l
+		return l, nil
+	}
+
+	if c.r.Mode() == ModeUnified {
+		//	8:16 rbps=2097152 wbps=max riops=max wiops=120
+		data, err := c.getFileContents("", "io.max")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			l, err := device(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			for key, n := range parseNestedKeys(fields[1:]) {
+				switch key {
+				case "rbps":
+					l.RBps = n
+				case "wbps":
+					l.WBps = n
+				case "riops":
+					l.RIOPS = n
+				case "wiops":
+					l.WIOPS = n
+				}
+			}
+		}
+		return sortIOLimits(devices), nil
+	}
+
+	//	8:0 1048576
+	for _, file := range []string{
+		"blkio.throttle.read_bps_device", "blkio.throttle.write_bps_device",
+		"blkio.throttle.read_iops_device", "blkio.throttle.write_iops_device",
+	} {
+		data, err := c.getFileContents("blkio", file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			l, err := device(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %s: %w", file, err)
+			}
+			switch file {
+			case "blkio.throttle.read_bps_device":
+				l.RBps = n
+			case "blkio.throttle.write_bps_device":
+				l.WBps = n
+			case "blkio.throttle.read_iops_device":
+				l.RIOPS = n
+			case "blkio.throttle.write_iops_device":
+				l.WIOPS = n
+			}
+		}
+	}
+	return sortIOLimits(devices), nil
+}
+
+// blockDeviceName returns the name of the block device "major:minor" from /sys/dev/block/<major:minor>/uevent.
+func (r *Reader) blockDeviceName(key string) string {
+	data, err := os.ReadFile(path.Join(r.sysRoot, "dev/block", key, "uevent"))
+	if err != nil {
+		return ""
+	}
+	name, _ := grepFirstMatch(string(data), "DEVNAME=", 1, "=")
+	return name
+}
+
+// parseDeviceNumber parses "major:minor" of a device.
+func parseDeviceNumber(key string) (int64, int64, error) {
+	major, minor, found := strings.Cut(key, ":")
+	if !found {
+		return 0, 0, fmt.Errorf("unexpected device number: %q", key)
+	}
+	ma, err := strconv.ParseInt(major, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot parse device major %q: %w", key, err)
+	}
+	mi, err := strconv.ParseInt(minor, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot parse device minor %q: %w", key, err)
+	}
+	return ma, mi, nil
+}
+
+// parseNestedKeys parses the "key=value" fields of a nested keyed file, "max" is parsed as Unlimited.
+func parseNestedKeys(fields []string) map[string]int64 {
+	m := map[string]int64{}
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		if value == "max" {
+			m[key] = Unlimited
+			continue
+		}
+		m[key], _ = strconv.ParseInt(value, 10, 64)
+	}
+	return m
+}
+
+func sortIOStats(devices map[string]*IOStat) []IOStat {
+	stats := make([]IOStat, 0, len(devices))
+	for _, s := range devices {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Major != stats[j].Major {
+			return stats[i].Major < stats[j].Major
+		}
+		return stats[i].Minor < stats[j].Minor
+	})
+	return stats
+}
+
+func sortIOLimits(devices map[string]*IOLimit) []IOLimit {
+	limits := make([]IOLimit, 0, len(devices))
+	for _, l := range devices {
+		limits = append(limits, *l)
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		if limits[i].Major != limits[j].Major {
+			return limits[i].Major < limits[j].Major
+		}
+		return limits[i].Minor < limits[j].Minor
+	})
+	return limits
+}
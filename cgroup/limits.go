@@ -0,0 +1,200 @@
+package cgroup
+
+import (
+	"context"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Limits are the CPU and memory limits of a cgroup, which may change at runtime,
+// eg: the in-place pod resize of kubernetes rewrites cpu.max and memory.max.
+type Limits struct {
+	// CPUQuota is the number of CPU cores available, see GetCPUQuota.
+	CPUQuota float64 `json:"cpu_quota" yaml:"cpu_quota" mapstructure:"cpu_quota"`
+	// MemoryLimit is the tightest memory limit in bytes, see EffectiveMemoryLimit.
+	MemoryLimit int64 `json:"memory_limit" yaml:"memory_limit" mapstructure:"memory_limit"`
+}
+
+// LimitChange is a notification of WatchLimits.
+type LimitChange struct {
+	// Old is the limits before the change.
+	Old Limits `json:"old" yaml:"old" mapstructure:"old"`
+	// New is the limits after the change.
+	New Limits `json:"new" yaml:"new" mapstructure:"new"`
+}
+
+// GetLimits returns the CPU and memory limits of the current process, see Limits.
+func GetLimits() Limits {
+	return defaultReader.GetLimits()
+}
+
+// GetLimits returns the CPU and memory limits, see Limits.
+func (c *Cgroup) GetLimits() Limits {
+	memoryLimit, _ := c.EffectiveMemoryLimit()
+	return Limits{CPUQuota: c.GetCPUQuota(), MemoryLimit: memoryLimit}
+}
+
+// LimitOrigin is the origin of a limit, see LimitSource.
+type LimitOrigin string
+
+const (
+	// OriginHost means no cgroup limit applies, the resources of the host are the limit.
+	OriginHost LimitOrigin = "host"
+	// OriginCgroup means the limit is set in the cgroup of the process.
+	OriginCgroup LimitOrigin = "cgroup"
+	// OriginAncestor means the limit is set in an ancestor of the cgroup, eg: the pod-level cgroup of kubernetes.
+	OriginAncestor LimitOrigin = "ancestor"
+	// OriginCPUSet means the CPU quota is capped by the number of CPUs in the cpuset.
+	OriginCPUSet LimitOrigin = "cpuset"
+)
+
+// LimitSource explains where a limit came from.
+type LimitSource struct {
+	Origin LimitOrigin `json:"origin" yaml:"origin" mapstructure:"origin"`
+	// Path is the cgroup path which sets the limit, it is relative to the cgroup namespace if any,
+	// eg: "/" is the container's own cgroup with a private cgroup namespace. It is empty for OriginHost.
+	Path string `json:"path" yaml:"path" mapstructure:"path"`
+	// File is the file of the limit, eg: memory.max, cpu.max, cpuset.cpus.effective, /sys/devices/system/cpu/online.
+	File string `json:"file" yaml:"file" mapstructure:"file"`
+}
+
+// String returns the source like "ancestor /kubepods.slice/kubepods-pod1.slice memory.max".
+func (s LimitSource) String() string {
+	return strings.Join(strings.Fields(string(s.Origin)+" "+s.Path+" "+s.File), " ")
+}
+
+// ExplainMemoryLimit returns the memory limit of the current process and its source, see Cgroup.ExplainMemoryLimit.
+func ExplainMemoryLimit() (int64, LimitSource) {
+	return defaultReader.ExplainMemoryLimit()
+}
+
+// ExplainMemoryLimit returns the tightest memory limit of the cgroup and its ancestors and where it came from,
+// see EffectiveMemoryLimit. It returns Unlimited and OriginHost if there is no limit.
+func (c *Cgroup) ExplainMemoryLimit() (int64, LimitSource) {
+	file := "memory.limit_in_bytes"
+	controller := "memory"
+	if c.r.Mode() == ModeUnified {
+		file, controller = "memory.max", ""
+	}
+	limit, limitPath := c.EffectiveMemoryLimit()
+	if limit == Unlimited {
+		return limit, LimitSource{Origin: OriginHost}
+	}
+	return limit, LimitSource{Origin: c.limitOrigin(controller, limitPath), Path: limitPath, File: file}
+}
+
+// ExplainCPUQuota returns the CPU quota of the current process and its source, see Cgroup.ExplainCPUQuota.
+func ExplainCPUQuota() (float64, LimitSource) {
+	return defaultReader.ExplainCPUQuota()
+}
+
+// ExplainCPUQuota returns the number of CPU cores available, see GetCPUQuota, and where it came from:
+// the CFS quota of the cgroup or an ancestor, the cpuset, or the online CPUs of the host.
+func (c *Cgroup) ExplainCPUQuota() (float64, LimitSource) {
+	unified := c.r.Mode() == ModeUnified
+	file, controller := "cpu.cfs_quota_us", "cpu"
+	if unified {
+		file, controller = "cpu.max", ""
+	}
+
+	// the quota file is absent if the cpu controller isn't enabled for the cgroup, the ancestors, the cpuset
+	// and the online CPUs still apply
+	cpuQuota, err := c.getCPUQuotaGeneric()
+	if err != nil {
+		cpuQuota = -1
+	}
+	source := LimitSource{Origin: OriginHost}
+	if cpuQuota > 0 {
+		_, cgroupPath, _ := c.resolve(controller)
+		source = LimitSource{Origin: OriginCgroup, Path: cgroupPath, File: file}
+	}
+	// An ancestor may set a tighter quota, eg: in multilevel containers, or the quota of a systemd slice.
+	// The tightest quota applies, as the memory limit does, see EffectiveMemoryLimit.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/685#issuecomment-674423728
+	if q, quotaPath := c.EffectiveCPUQuota(); q > 0 && (cpuQuota <= 0 || q < cpuQuota) {
+		cpuQuota = q
+		source = LimitSource{Origin: c.limitOrigin(controller, quotaPath), Path: quotaPath, File: file}
+	}
+
+	// the tasks pinned to the cpuset cannot use more CPUs, eg: docker run --cpuset-cpus
+	if n := float64(c.GetCPUSet().Count()); n > 0 && (cpuQuota <= 0 || cpuQuota > n) {
+		file, controller = "cpuset.effective_cpus", "cpuset"
+		if unified {
+			file, controller = "cpuset.cpus.effective", ""
+		}
+		_, cgroupPath, _ := c.resolve(controller)
+		return n, LimitSource{Origin: OriginCPUSet, Path: cgroupPath, File: file}
+	}
+	if cpuQuota <= 0 {
+		if n := c.getOnlineCPUCount(); n > 0 {
+			return n, LimitSource{Origin: OriginHost, File: path.Join(c.r.sysRoot, "devices/system/cpu/online")}
+		}
+		return float64(runtime.NumCPU()), LimitSource{Origin: OriginHost}
+	}
+	return cpuQuota, source
+}
+
+// limitOrigin returns OriginCgroup if limitPath is the cgroup of the controller, or OriginAncestor.
+func (c *Cgroup) limitOrigin(controller, limitPath string) LimitOrigin {
+	if _, cgroupPath, err := c.resolve(controller); err == nil && cgroupPath != limitPath {
+		return OriginAncestor
+	}
+	return OriginCgroup
+}
+
+// WatchLimits watches the limits of the current process, see Cgroup.WatchLimits.
+func WatchLimits(ctx context.Context, interval time.Duration) (<-chan LimitChange, error) {
+	return defaultReader.WatchLimits(ctx, interval)
+}
+
+// WatchLimits polls the limits of the cgroup and its ancestors every interval, a LimitChange is delivered on the
+// returned channel each time the limits change. The channel is closed when ctx is done.
+//   - interval = 0: 1 second
+//
+// The limit files are polled rather than watched with inotify, since the ancestors may change as well.
+func (c *Cgroup) WatchLimits(ctx context.Context, interval time.Duration) (<-chan LimitChange, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	// resolve the cgroup first, so that a wrong cgroup fails now rather than never changes
+	if _, err := c.cgroupDir(c.limitController()); err != nil {
+		return nil, err
+	}
+
+	last := c.GetLimits()
+	ch := make(chan LimitChange)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			cur := c.GetLimits()
+			if cur == last {
+				continue
+			}
+			select {
+			case ch <- LimitChange{Old: last, New: cur}:
+			case <-ctx.Done():
+				return
+			}
+			last = cur
+		}
+	}()
+	return ch, nil
+}
+
+// limitController returns the controller of cpu.max and memory.max, or the v1 memory controller.
+func (c *Cgroup) limitController() string {
+	if c.r.Mode() == ModeUnified {
+		return ""
+	}
+	return "memory"
+}
@@ -0,0 +1,212 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Manager writes the settings of a cgroup v2 and manages its children, eg: a subtree delegated to the current
+// user by systemd with Delegate=yes. The embedded Cgroup reads the statistics of the managed cgroup.
+//
+// Manager is only available in unified mode. The "no internal processes" rule of cgroup v2 applies: the controllers
+// can only be enabled for the children with EnableControllers if the cgroup itself has no processes, so a process
+// usually moves itself into a leaf child before confining the others.
+// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#delegation
+type Manager struct {
+	*Cgroup
+	dir  string
+	path string
+}
+
+// NewManager returns a Manager of the current process's cgroup, see Cgroup.NewManager.
+func NewManager() (*Manager, error) {
+	return defaultReader.NewManager()
+}
+
+// NewManager returns a Manager of the cgroup. The Manager is pinned to the cgroup path resolved now,
+// it keeps managing and reading the same cgroup after the process moves itself into a child.
+func (c *Cgroup) NewManager() (*Manager, error) {
+	if m := c.r.Mode(); m != ModeUnified {
+		return nil, fmt.Errorf("cgroup manager is not available in %s mode", m)
+	}
+	_, cgroupPath, err := c.resolve("")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := c.cgroupDir("")
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := c.r.Open(cgroupPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{Cgroup: pinned, dir: dir, path: cgroupPath}, nil
+}
+
+// Path returns the cgroup path of the managed cgroup, eg: /user.slice/user-1000.slice/app.service/job-1
+func (m *Manager) Path() string {
+	return m.path
+}
+
+// Dir returns the directory of the managed cgroup, eg: /sys/fs/cgroup/user.slice/user-1000.slice/app.service/job-1
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// NewChild creates the child cgroup named name and returns its Manager.
+// It returns an error wrapping os.ErrExist if the child exists.
+func (m *Manager) NewChild(name string) (*Manager, error) {
+	if err := validateChildName(name); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(path.Join(m.dir, name), 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create cgroup %q: %w", path.Join(m.path, name), err)
+	}
+	return m.Child(name)
+}
+
+// Child returns the Manager of the existing child cgroup named name.
+func (m *Manager) Child(name string) (*Manager, error) {
+	if err := validateChildName(name); err != nil {
+		return nil, err
+	}
+	childPath := path.Join(m.path, name)
+	c, err := m.r.Open(childPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{Cgroup: c, dir: path.Join(m.dir, name), path: childPath}, nil
+}
+
+// Children returns the names of the child cgroups.
+func (m *Manager) Children() ([]string, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Remove removes the managed cgroup, it fails if the cgroup has any process or child cgroup.
+func (m *Manager) Remove() error {
+	// rmdir removes a cgroup directory with its interface files.
+	if err := os.Remove(m.dir); err != nil {
+		return fmt.Errorf("cannot remove cgroup %q: %w", m.path, err)
+	}
+	return nil
+}
+
+// EnableControllers enables the controllers for the children in "cgroup.subtree_control", eg: "memory", "cpu", "pids".
+// The controllers must be enabled before the children can set their limits.
+func (m *Manager) EnableControllers(controllers ...string) error {
+	fields := make([]string, len(controllers))
+	for i, controller := range controllers {
+		fields[i] = "+" + controller
+	}
+	return m.writeFile("cgroup.subtree_control", strings.Join(fields, " "))
+}
+
+// SetMemoryMax writes the hard memory limit in bytes to "memory.max", Unlimited removes the limit.
+func (m *Manager) SetMemoryMax(bytes int64) error {
+	return m.writeFile("memory.max", formatMax(bytes))
+}
+
+// SetMemoryHigh writes the memory throttle limit in bytes to "memory.high", Unlimited removes the limit.
+func (m *Manager) SetMemoryHigh(bytes int64) error {
+	return m.writeFile("memory.high", formatMax(bytes))
+}
+
+// SetCPUMax writes the CPU quota in cores to "cpu.max", eg: 1.5 allows 150ms in each 100ms period.
+//   - cores <= 0: remove the quota
+//   - period = 0: 100ms, the kernel default
+func (m *Manager) SetCPUMax(cores float64, period time.Duration) error {
+	if period <= 0 {
+		period = 100 * time.Millisecond
+	}
+	quota := "max"
+	if cores > 0 {
+		quota = strconv.FormatInt(int64(cores*float64(period.Microseconds())), 10)
+	}
+	return m.writeFile("cpu.max", fmt.Sprintf("%s %d", quota, period.Microseconds()))
+}
+
+// SetPidsMax writes the maximum number of processes and threads to "pids.max", Unlimited removes the limit.
+func (m *Manager) SetPidsMax(n int64) error {
+	return m.writeFile("pids.max", formatMax(n))
+}
+
+// AddProc moves the process with all its threads into the managed cgroup by writing "cgroup.procs".
+// Moving a process across the delegated subtrees requires the write permission of their common ancestor.
+func (m *Manager) AddProc(pid int) error {
+	return m.writeFile("cgroup.procs", strconv.Itoa(pid))
+}
+
+// Procs returns the PIDs of the processes in the managed cgroup from "cgroup.procs".
+func (m *Manager) Procs() ([]int, error) {
+	data, err := os.ReadFile(path.Join(m.dir, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse cgroup.procs: %w", err)
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// Freeze stops all the processes of the managed cgroup and its descendants by writing "cgroup.freeze",
+// the freezing is asynchronous, see Frozen.
+func (m *Manager) Freeze() error {
+	return m.writeFile("cgroup.freeze", "1")
+}
+
+// Thaw resumes the processes stopped by Freeze.
+func (m *Manager) Thaw() error {
+	return m.writeFile("cgroup.freeze", "0")
+}
+
+// Frozen reports whether the managed cgroup is completely frozen from "frozen" in "cgroup.events".
+func (m *Manager) Frozen() (bool, error) {
+	data, err := os.ReadFile(path.Join(m.dir, "cgroup.events"))
+	if err != nil {
+		return false, err
+	}
+	return parseKeyValues(string(data))["frozen"] == 1, nil
+}
+
+func (m *Manager) writeFile(statFileName, value string) error {
+	if err := os.WriteFile(path.Join(m.dir, statFileName), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("cannot write %q to %s of cgroup %q: %w", value, statFileName, m.path, err)
+	}
+	return nil
+}
+
+func validateChildName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid cgroup name %q", name)
+	}
+	return nil
+}
+
+// formatMax formats a limit of cgroup v2, Unlimited is "max".
+func formatMax(n int64) string {
+	if n == Unlimited {
+		return "max"
+	}
+	return strconv.FormatInt(n, 10)
+}
@@ -1,51 +1,167 @@
 package cgroup
 
 import (
+	"fmt"
 	"strconv"
-	"strings"
 )
 
-// GetMemoryLimit returns cgroup memory limit from "memory.limit_in_bytes" file.
+// GetMemoryLimit returns cgroup memory limit from "memory.limit_in_bytes" file ("memory.max" in cgroup v2),
+// Unlimited if there is no limit.
 func GetMemoryLimit() int64 {
+	return defaultReader.GetMemoryLimit()
+}
+
+// GetMemoryLimit returns cgroup memory limit from "memory.limit_in_bytes" file.
+func (c *Cgroup) GetMemoryLimit() int64 {
 	// Try determining the amount of memory inside docker container.
 	// See https://stackoverflow.com/questions/42187085/check-mem-limit-within-a-docker-container
 	//
 	// Read memory limit according to https://unix.stackexchange.com/questions/242718/how-to-find-out-how-much-memory-lxc-container-is-allowed-to-consume
 	// This should properly determine the limit inside lxc container.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/84
-	return getMemStat("memory.limit_in_bytes", "memory.max")
+	return normalizeMemoryLimit(c.getMemStat("memory.limit_in_bytes", "memory.max"))
 }
 
 // GetMemoryUsage returns memory usage from "memory.usage_in_bytes" file.
 // memory.usage_in_bytes ~= free.used + free.(buff/cache) - (buff)
 func GetMemoryUsage() int64 {
-	return getMemStat("memory.usage_in_bytes", "memory.current")
+	return defaultReader.GetMemoryUsage()
+}
+
+// GetMemoryUsage returns memory usage from "memory.usage_in_bytes" file.
+func (c *Cgroup) GetMemoryUsage() int64 {
+	return c.getMemStat("memory.usage_in_bytes", "memory.current")
 }
 
 // GetMemoryFailcnt returns memory failcnt from "memory.failcnt" file.
 func GetMemoryFailcnt() int64 {
-	return getMemStat("memory.failcnt", "")
+	return defaultReader.GetMemoryFailcnt()
+}
+
+// GetMemoryFailcnt returns memory failcnt from "memory.failcnt" file.
+func (c *Cgroup) GetMemoryFailcnt() int64 {
+	return c.getMemStat("memory.failcnt", "")
 }
 
 // GetMemoryMaxUsage returns maximum memory usage from "memory.max_usage_in_bytes" file.
 func GetMemoryMaxUsage() int64 {
-	return getMemStat("memory.max_usage_in_bytes", "memory.max_usage")
+	return defaultReader.GetMemoryMaxUsage()
 }
 
-// GetMemoryHierarchicalLimit returns hierarchical memory limit from "memory.hierarchical_memory_limit" file.
+// GetMemoryMaxUsage returns maximum memory usage from "memory.max_usage_in_bytes" file.
+func (c *Cgroup) GetMemoryMaxUsage() int64 {
+	return c.getMemStat("memory.max_usage_in_bytes", "memory.max_usage")
+}
+
+// GetMemoryHierarchicalLimit returns hierarchical memory limit from "memory.hierarchical_memory_limit" file,
+// or the tightest "memory.max" of the cgroup and its ancestors in cgroup v2, see EffectiveMemoryLimit.
 func GetMemoryHierarchicalLimit() int64 {
-	return getMemStat("memory.hierarchical_memory_limit", "memory.high")
+	return defaultReader.GetMemoryHierarchicalLimit()
+}
+
+// GetMemoryHierarchicalLimit returns hierarchical memory limit, see GetMemoryHierarchicalLimit.
+func (c *Cgroup) GetMemoryHierarchicalLimit() int64 {
+	if c.r.Mode() == ModeUnified {
+		// memory.high is the throttle limit rather than the hierarchical limit, see GetMemoryLimits.
+		limit, _ := c.EffectiveMemoryLimit()
+		return limit
+	}
+	return normalizeMemoryLimit(c.getMemStat("memory.hierarchical_memory_limit", ""))
+}
+
+// MemoryLimits are the memory protection, throttle and limit settings of a cgroup in bytes,
+// Unlimited means the setting is not set.
+// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
+type MemoryLimits struct {
+	// Min is the hard protection from "memory.min", the memory is never reclaimed below it, 0 in cgroup v1.
+	Min int64 `json:"min" yaml:"min" mapstructure:"min"`
+	// Low is the best-effort protection from "memory.low", 0 in cgroup v1.
+	Low int64 `json:"low" yaml:"low" mapstructure:"low"`
+	// High is the throttle limit from "memory.high", the cgroup is throttled and reclaimed heavily over it,
+	// Unlimited in cgroup v1.
+	High int64 `json:"high" yaml:"high" mapstructure:"high"`
+	// Max is the hard limit from "memory.max" ("memory.limit_in_bytes" in cgroup v1), the OOM killer is invoked over it.
+	Max int64 `json:"max" yaml:"max" mapstructure:"max"`
+	// SwapMax is the swap limit, see GetSwapLimit. It is Unlimited if the swap accounting is disabled.
+	SwapMax int64 `json:"swap_max" yaml:"swap_max" mapstructure:"swap_max"`
+	// SoftLimit is the cgroup v1 "memory.soft_limit_in_bytes", the cgroup is reclaimed to it under the
+	// global memory pressure, Unlimited in cgroup v2.
+	SoftLimit int64 `json:"soft_limit" yaml:"soft_limit" mapstructure:"soft_limit"`
+}
+
+// GetMemoryLimits returns the memory settings of the current process, see MemoryLimits.
+func GetMemoryLimits() (*MemoryLimits, error) {
+	return defaultReader.GetMemoryLimits()
+}
+
+// GetMemoryLimits returns the memory settings, see MemoryLimits.
+func (c *Cgroup) GetMemoryLimits() (*MemoryLimits, error) {
+	limits := &MemoryLimits{High: Unlimited, SoftLimit: Unlimited, SwapMax: Unlimited}
+	if swapMax, err := c.GetSwapLimit(); err == nil {
+		limits.SwapMax = swapMax
+	}
+
+	var err error
+	if c.r.Mode() == ModeUnified {
+		for _, l := range []struct {
+			statFileName string
+			value        *int64
+		}{
+			{"memory.min", &limits.Min},
+			{"memory.low", &limits.Low},
+			{"memory.high", &limits.High},
+			{"memory.max", &limits.Max},
+		} {
+			if *l.value, err = c.getStatGeneric("", l.statFileName); err != nil {
+				return nil, err
+			}
+		}
+		return limits, nil
+	}
+
+	// See https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt
+	if limits.Max, err = c.getStatGeneric("memory", "memory.limit_in_bytes"); err != nil {
+		return nil, err
+	}
+	limits.Max = normalizeMemoryLimit(limits.Max)
+	if softLimit, err := c.getStatGeneric("memory", "memory.soft_limit_in_bytes"); err == nil {
+		limits.SoftLimit = normalizeMemoryLimit(softLimit)
+	}
+	return limits, nil
 }
 
+// GetMemoryOOMControl returns 1 if the OOM killer is disabled in "memory.oom_control" file, otherwise 0.
+//
+// Deprecated: use GetMemoryEvents, which parses all the fields of memory.oom_control and memory.events.
 func GetMemoryOOMControl() int64 {
-	return getMemStat("memory.oom_control", "memory.oom_kill_disable")
+	return defaultReader.GetMemoryOOMControl()
 }
 
-// GetHierarchicalMemoryLimit returns hierarchical memory limit from "memory.stat" file.
+// GetMemoryOOMControl returns 1 if the OOM killer is disabled, otherwise 0.
+//
+// Deprecated: use Cgroup.GetMemoryEvents.
+func (c *Cgroup) GetMemoryOOMControl() int64 {
+	if events, err := c.GetMemoryEvents(); err == nil && events.OOMKillDisable {
+		return 1
+	}
+	return 0
+}
+
+// GetHierarchicalMemoryLimit returns hierarchical memory limit from "memory.stat" file,
+// Unlimited if there is no limit.
 // https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt
 func GetHierarchicalMemoryLimit() int64 {
+	return defaultReader.GetHierarchicalMemoryLimit()
+}
+
+// GetHierarchicalMemoryLimit returns hierarchical memory limit from "memory.stat" file,
+// or the same value as GetMemoryHierarchicalLimit in cgroup v2.
+func (c *Cgroup) GetHierarchicalMemoryLimit() int64 {
+	if c.r.Mode() == ModeUnified {
+		return c.GetMemoryHierarchicalLimit()
+	}
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/699
-	data, err := getFileContents("memory.stat", "/sys/fs/cgroup/memory", "/proc/self/cgroup", "memory")
+	data, err := c.getFileContents("memory", "memory.stat")
 	if err != nil {
 		return 0
 	}
@@ -54,25 +170,13 @@ func GetHierarchicalMemoryLimit() int64 {
 		return 0
 	}
 	n, _ := strconv.ParseInt(memStat, 10, 64)
-	return n
+	return normalizeMemoryLimit(n)
 }
 
-func getMemStat(statFileName string, v2StatFileName string) int64 {
-	n, err := getStatGeneric(statFileName, "/sys/fs/cgroup/memory", "/proc/self/cgroup", "memory")
-	if err == nil {
-		return n
-	}
-
-	if v2StatFileName == "" {
-		return 0
-	}
-
-	// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
-	n, err = getStatGeneric(v2StatFileName, "/sys/fs/cgroup", "/proc/self/cgroup", "")
-	if err != nil {
-		return 0
-	}
-	return n
+// getMemStat reads statFileName in legacy/hybrid mode, or v2StatFileName in unified mode.
+// v2StatFileName is empty if the stat is not available in cgroup v2.
+func (c *Cgroup) getMemStat(statFileName string, v2StatFileName string) int64 {
+	return c.getControllerStat("memory", statFileName, v2StatFileName)
 }
 
 // MemoryStat https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt
@@ -115,23 +219,21 @@ type MemoryStat struct {
 	TotalUnevictable        int64 `json:"total_unevictable" yaml:"total_unevictable" mapstructure:"total_unevictable"`
 }
 
-// GetMemoryStat returns memory statistics for the current process.
+// GetMemoryStat returns memory statistics for the current process, only available in legacy/hybrid mode.
 func GetMemoryStat() (*MemoryStat, error) {
-	data, err := getFileContents("memory.stat", "/sys/fs/cgroup/memory", "/proc/self/cgroup", "memory")
+	return defaultReader.GetMemoryStat()
+}
+
+// GetMemoryStat returns cgroup v1 memory statistics, see GetMemoryStat.
+func (c *Cgroup) GetMemoryStat() (*MemoryStat, error) {
+	if m := c.r.Mode(); m == ModeUnified {
+		return nil, fmt.Errorf("cgroup v1 memory.stat is not available in %s mode", m)
+	}
+	m, err := c.getKeyValues("memory", "memory.stat")
 	if err != nil {
 		return nil, err
 	}
 
-	m := map[string]int64{}
-	lines := strings.Split(data, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		segments := strings.Split(line, " ")
-		if len(segments) >= 2 {
-			m[segments[0]], _ = strconv.ParseInt(segments[1], 10, 64)
-		}
-	}
-
 	return &MemoryStat{
 		Cache:                   m["cache"],
 		Rss:                     m["rss"],
@@ -225,22 +327,21 @@ type MemoryStatV2 struct {
 	ThpCollapseAlloc       int64 `json:"thp_collapse_alloc" yaml:"thp_collapse_alloc" mapstructure:"thp_collapse_alloc"`
 }
 
+// GetMemoryStatV2 returns memory statistics for the current process, only available in unified mode.
 func GetMemoryStatV2() (*MemoryStatV2, error) {
-	data, err := getFileContents("memory.stat", "/sys/fs/cgroup", "/proc/self/cgroup", "")
+	return defaultReader.GetMemoryStatV2()
+}
+
+// GetMemoryStatV2 returns cgroup v2 memory statistics, see GetMemoryStatV2.
+func (c *Cgroup) GetMemoryStatV2() (*MemoryStatV2, error) {
+	if m := c.r.Mode(); m != ModeUnified {
+		return nil, fmt.Errorf("cgroup v2 memory.stat is not available in %s mode", m)
+	}
+	m, err := c.getKeyValues("", "memory.stat")
 	if err != nil {
 		return nil, err
 	}
 
-	m := map[string]int64{}
-	lines := strings.Split(data, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		segments := strings.Split(line, " ")
-		if len(segments) >= 2 {
-			m[segments[0]], _ = strconv.ParseInt(segments[1], 10, 64)
-		}
-	}
-
 	return &MemoryStatV2{
 		Anon:                   m["anon"],
 		File:                   m["file"],
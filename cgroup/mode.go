@@ -0,0 +1,72 @@
+package cgroup
+
+// CgroupMode is the layout of the cgroup hierarchies mounted on the host.
+type CgroupMode int
+
+const (
+	// ModeNone means no cgroup hierarchy is mounted, eg: on non-linux systems.
+	ModeNone CgroupMode = iota
+	// ModeLegacy means only cgroup v1 hierarchies are mounted.
+	ModeLegacy
+	// ModeHybrid means the controllers are mounted as cgroup v1, with an additional cgroup v2
+	// hierarchy (usually /sys/fs/cgroup/unified) which is only used for process tracking.
+	ModeHybrid
+	// ModeUnified means only the cgroup v2 unified hierarchy is mounted.
+	ModeUnified
+)
+
+// String returns the name of the mode.
+func (m CgroupMode) String() string {
+	switch m {
+	case ModeLegacy:
+		return "legacy"
+	case ModeHybrid:
+		return "hybrid"
+	case ModeUnified:
+		return "unified"
+	default:
+		return "none"
+	}
+}
+
+// Mode returns the cgroup mode of the host, it is detected from the mount table and
+// the filesystem magic of /sys/fs/cgroup.
+//
+// In ModeLegacy and ModeHybrid the controllers are read from the v1 files,
+// in ModeUnified from the v2 files.
+func Mode() CgroupMode {
+	return defaultReader.Mode()
+}
+
+// Mode returns the cgroup mode of the host, see Mode. It is detected once and cached until Refresh.
+func (r *Reader) Mode() CgroupMode {
+	_, mode, _ := r.cachedMounts()
+	return mode
+}
+
+// modeFromMounts detects the cgroup mode from the mounted hierarchies.
+func modeFromMounts(mounts []Mount) CgroupMode {
+	var v1, v2 bool
+	for _, m := range mounts {
+		if m.IsV2() {
+			v2 = true
+			continue
+		}
+		// a named hierarchy without controller, eg: "name=systemd", does not hold any limit
+		for _, c := range m.Controllers {
+			if _, ok := v1Controllers[c]; ok {
+				v1 = true
+			}
+		}
+	}
+
+	switch {
+	case v1 && v2:
+		return ModeHybrid
+	case v1:
+		return ModeLegacy
+	case v2:
+		return ModeUnified
+	}
+	return ModeNone
+}
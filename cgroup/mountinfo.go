@@ -0,0 +1,213 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Mount is a cgroup hierarchy mounted in the current mount namespace.
+type Mount struct {
+	// MountPoint is where the hierarchy is mounted, eg: /sys/fs/cgroup/memory
+	MountPoint string `json:"mount_point" yaml:"mount_point" mapstructure:"mount_point"`
+	// Root is the cgroup path mounted at MountPoint, it is not "/" for the bind mounts and
+	// the cgroup namespaces, eg: /docker/<container-id>
+	Root string `json:"root" yaml:"root" mapstructure:"root"`
+	// FSType is "cgroup" for cgroup v1, "cgroup2" for cgroup v2.
+	FSType string `json:"fs_type" yaml:"fs_type" mapstructure:"fs_type"`
+	// Controllers are the v1 controllers mounted in the hierarchy, eg: [cpu cpuacct] or [name=systemd].
+	// It is empty for cgroup v2.
+	Controllers []string `json:"controllers" yaml:"controllers" mapstructure:"controllers"`
+}
+
+// IsV2 returns true if the mount is the cgroup v2 unified hierarchy.
+func (m Mount) IsV2() bool {
+	return m.FSType == "cgroup2"
+}
+
+// HasController returns true if the controller is mounted in the hierarchy,
+// an empty controller means the cgroup v2 unified hierarchy.
+func (m Mount) HasController(controller string) bool {
+	if controller == "" {
+		return m.IsV2()
+	}
+	for _, c := range m.Controllers {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// v1Controllers are the controllers of cgroup v1, see /proc/cgroups
+var v1Controllers = map[string]struct{}{
+	"cpu": {}, "cpuacct": {}, "cpuset": {}, "memory": {}, "devices": {}, "freezer": {}, "net_cls": {},
+	"blkio": {}, "perf_event": {}, "net_prio": {}, "hugetlb": {}, "pids": {}, "rdma": {}, "misc": {},
+}
+
+// Mounts returns the cgroup hierarchies mounted in the current mount namespace from /proc/self/mountinfo.
+func Mounts() ([]Mount, error) {
+	return defaultReader.Mounts()
+}
+
+// Mounts returns the cgroup hierarchies from <procRoot>/self/mountinfo, the mount points under /sys
+// are relocated to sysRoot, eg: /sys/fs/cgroup/memory -> /host/sys/fs/cgroup/memory.
+// The mount table is read once and cached until Refresh.
+func (r *Reader) Mounts() ([]Mount, error) {
+	mounts, _, err := r.cachedMounts()
+	if err != nil {
+		return nil, err
+	}
+	return append([]Mount(nil), mounts...), nil
+}
+
+func (r *Reader) readMounts() ([]Mount, error) {
+	data, err := os.ReadFile(path.Join(r.procRoot, "self/mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	mounts := parseMountInfo(string(data))
+	for i := range mounts {
+		mounts[i].MountPoint = r.sysPath(mounts[i].MountPoint)
+	}
+	return mounts, nil
+}
+
+// parseMountInfo parses the cgroup mounts from the content of /proc/<pid>/mountinfo.
+// See https://man7.org/linux/man-pages/man5/proc.5.html
+//
+//	36 32 0:32 /docker/ab12 /sys/fs/cgroup/memory rw,relatime shared:15 - cgroup cgroup rw,memory
+//	(1)(2)(3)   (4)         (5)                   (6)         (7)      (8) (9)   (10)   (11)
+//	42 32 0:38 / /sys/fs/cgroup/unified rw,relatime shared:21 - cgroup2 cgroup2 rw,nsdelegate
+func parseMountInfo(data string) []Mount {
+	var mounts []Mount
+	for _, line := range strings.Split(data, "\n") {
+		// the optional fields (7) are variable, so split the line by the separator (8)
+		before, after, found := strings.Cut(line, " - ")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(before)
+		superFields := strings.Fields(after)
+		if len(fields) < 5 || len(superFields) < 3 {
+			continue
+		}
+
+		m := Mount{
+			MountPoint: unescapeMountPath(fields[4]),
+			Root:       unescapeMountPath(fields[3]),
+			FSType:     superFields[0],
+		}
+		switch m.FSType {
+		case "cgroup2":
+		case "cgroup":
+			// the super options contain the controllers, and the flags such as "rw", "xattr", "release_agent=..."
+			for _, opt := range strings.Split(superFields[2], ",") {
+				if _, ok := v1Controllers[opt]; ok || strings.HasPrefix(opt, "name=") {
+					m.Controllers = append(m.Controllers, opt)
+				}
+			}
+		default:
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes of mountinfo, eg: "\040" for a space.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseProcCgroup parses the content of /proc/<pid>/cgroup into a map of controller -> cgroup path,
+// the cgroup v2 unified hierarchy uses the empty controller.
+//
+//	5:cpu,cpuacct:/kubepods/pod1
+//	1:name=systemd:/
+//	0::/kubepods/pod1
+func parseProcCgroup(data string) map[string]string {
+	paths := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		// the path may contain ":"
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// controllerDir returns the directory of cgroupPath in the hierarchy where the controller is mounted,
+// an empty controller means the cgroup v2 unified hierarchy.
+func controllerDir(mounts []Mount, controller, cgroupPath string) (string, error) {
+	mount, err := findMount(mounts, controller, cgroupPath)
+	if err != nil {
+		return "", err
+	}
+	return mountDir(mount, cgroupPath), nil
+}
+
+// findMount returns the mount of the hierarchy where the controller is mounted.
+func findMount(mounts []Mount, controller, cgroupPath string) (*Mount, error) {
+	var mount *Mount
+	for i := range mounts {
+		m := &mounts[i]
+		if !m.HasController(controller) {
+			continue
+		}
+		// a controller may be mounted more than once, prefer the mount whose root contains cgroupPath.
+		if mount == nil || (!isSubPath(mount.Root, cgroupPath) && isSubPath(m.Root, cgroupPath)) {
+			mount = m
+		}
+	}
+	if mount == nil {
+		if controller == "" {
+			return nil, fmt.Errorf("cannot find the cgroup2 mount")
+		}
+		return nil, fmt.Errorf("cannot find the cgroup mount of controller %q", controller)
+	}
+	return mount, nil
+}
+
+// mountDir returns the directory of cgroupPath in the mount.
+func mountDir(mount *Mount, cgroupPath string) string {
+	// eg: docker without cgroup namespace bind mounts /docker/<id> at /sys/fs/cgroup/memory, and
+	// /proc/self/cgroup shows /docker/<id>, so the cgroup is the mount point itself.
+	if !isSubPath(mount.Root, cgroupPath) {
+		// the cgroup is outside the mount, the mount point is the closest directory we can see.
+		return mount.MountPoint
+	}
+	return path.Join(mount.MountPoint, strings.TrimPrefix(cgroupPath, mount.Root))
+}
+
+// isSubPath returns true if p is root or under root.
+func isSubPath(root, p string) bool {
+	if root == "/" || root == p {
+		return true
+	}
+	return strings.HasPrefix(p, strings.TrimSuffix(root, "/")+"/")
+}
@@ -0,0 +1,55 @@
+package cgroup
+
+import "fmt"
+
+// GetPidsLimit returns the maximum number of processes and threads from "pids.max" file,
+// math.MaxInt64 if there is no limit, or 0 if the pids controller is not available.
+func GetPidsLimit() int64 {
+	return defaultReader.GetPidsLimit()
+}
+
+// GetPidsLimit returns the maximum number of processes and threads, see GetPidsLimit.
+func (c *Cgroup) GetPidsLimit() int64 {
+	// See https://www.kernel.org/doc/Documentation/cgroup-v1/pids.txt
+	return c.getPidsStat("pids.max")
+}
+
+// GetPidsCurrent returns the number of processes and threads from "pids.current" file.
+func GetPidsCurrent() int64 {
+	return defaultReader.GetPidsCurrent()
+}
+
+// GetPidsCurrent returns the number of processes and threads, see GetPidsCurrent.
+func (c *Cgroup) GetPidsCurrent() int64 {
+	return c.getPidsStat("pids.current")
+}
+
+// PidsEvents are the counters of the pids events.
+type PidsEvents struct {
+	// Max is the number of times fork/clone failed because of pids.max,
+	// these are the "fork/exec: resource temporarily unavailable" errors.
+	Max int64 `json:"max" yaml:"max" mapstructure:"max"`
+}
+
+// GetPidsEvents returns the pids events of the current process from "pids.events" file.
+func GetPidsEvents() (*PidsEvents, error) {
+	return defaultReader.GetPidsEvents()
+}
+
+// GetPidsEvents returns the pids events, see GetPidsEvents.
+func (c *Cgroup) GetPidsEvents() (*PidsEvents, error) {
+	controller := "pids"
+	if c.r.Mode() == ModeUnified {
+		controller = ""
+	}
+	m, err := c.getKeyValues(controller, "pids.events")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read pids.events: %w", err)
+	}
+	return &PidsEvents{Max: m["max"]}, nil
+}
+
+// getPidsStat reads statFileName of the pids controller, the files are the same in cgroup v1 and v2.
+func (c *Cgroup) getPidsStat(statFileName string) int64 {
+	return c.getControllerStat("pids", statFileName, statFileName)
+}
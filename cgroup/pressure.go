@@ -0,0 +1,180 @@
+package cgroup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PressureResource is the resource of the Pressure Stall Information.
+type PressureResource string
+
+const (
+	PressureCPU    PressureResource = "cpu"
+	PressureMemory PressureResource = "memory"
+	PressureIO     PressureResource = "io"
+)
+
+// PressureValues are the stall ratios of a PSI line.
+type PressureValues struct {
+	// Avg10 is the percentage of the time stalled in the last 10 seconds.
+	Avg10 float64 `json:"avg10" yaml:"avg10" mapstructure:"avg10"`
+	// Avg60 is the percentage of the time stalled in the last 60 seconds.
+	Avg60 float64 `json:"avg60" yaml:"avg60" mapstructure:"avg60"`
+	// Avg300 is the percentage of the time stalled in the last 300 seconds.
+	Avg300 float64 `json:"avg300" yaml:"avg300" mapstructure:"avg300"`
+	// Total is the total stall time in microseconds.
+	Total int64 `json:"total" yaml:"total" mapstructure:"total"`
+}
+
+// Pressure is the Pressure Stall Information of a resource.
+// See https://www.kernel.org/doc/html/latest/accounting/psi.html
+type Pressure struct {
+	// Some is the share of the time in which at least some tasks are stalled on the resource.
+	Some PressureValues `json:"some" yaml:"some" mapstructure:"some"`
+	// Full is the share of the time in which all non-idle tasks are stalled on the resource simultaneously.
+	Full PressureValues `json:"full" yaml:"full" mapstructure:"full"`
+}
+
+// GetSystemPressure returns the system-wide pressure of the resource from "/proc/pressure/<resource>".
+func GetSystemPressure(resource PressureResource) (*Pressure, error) {
+	return defaultReader.GetSystemPressure(resource)
+}
+
+// GetSystemPressure returns the system-wide pressure of the resource, see GetSystemPressure.
+func (r *Reader) GetSystemPressure(resource PressureResource) (*Pressure, error) {
+	data, err := os.ReadFile(r.systemPressurePath(resource))
+	if err != nil {
+		return nil, err
+	}
+	return parsePressure(string(data))
+}
+
+// GetPressure returns the pressure of the resource of the current process from "<resource>.pressure",
+// it requires the cgroup v2 hierarchy, which is also mounted in hybrid mode.
+func GetPressure(resource PressureResource) (*Pressure, error) {
+	return defaultReader.GetPressure(resource)
+}
+
+// GetPressure returns the pressure of the resource, see GetPressure.
+func (c *Cgroup) GetPressure(resource PressureResource) (*Pressure, error) {
+	data, err := c.getFileContents("", string(resource)+".pressure")
+	if err != nil {
+		return nil, err
+	}
+	return parsePressure(data)
+}
+
+// parsePressure parses the content of a PSI file, "full" is absent in /proc/pressure/cpu before linux 5.13.
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(data string) (*Pressure, error) {
+	p := &Pressure{}
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var values *PressureValues
+		switch fields[0] {
+		case "some":
+			values = &p.Some
+		case "full":
+			values = &p.Full
+		default:
+			return nil, fmt.Errorf("unexpected pressure line: %q", line)
+		}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			var err error
+			switch key {
+			case "avg10":
+				values.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				values.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				values.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				values.Total, err = strconv.ParseInt(value, 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse pressure %q: %w", field, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (r *Reader) systemPressurePath(resource PressureResource) string {
+	return path.Join(r.procRoot, "pressure", string(resource))
+}
+
+// PressureTrigger is a PSI threshold, it fires when the tasks are stalled on Resource for more than Stall
+// in a Window, eg: 150ms in 1s.
+type PressureTrigger struct {
+	Resource PressureResource
+	// Full selects the "full" stall instead of the "some" stall.
+	Full bool
+	// Stall is the threshold of the stall time in the window.
+	Stall time.Duration
+	// Window is the tracking window, between 500ms and 10s. The unprivileged users can only use
+	// multiples of 2s for the system-wide triggers.
+	Window time.Duration
+}
+
+// String returns the trigger in the format of the kernel, eg: "some 150000 1000000".
+func (t PressureTrigger) String() string {
+	kind := "some"
+	if t.Full {
+		kind = "full"
+	}
+	return fmt.Sprintf("%s %d %d", kind, t.Stall.Microseconds(), t.Window.Microseconds())
+}
+
+func (t PressureTrigger) validate() error {
+	if t.Window < 500*time.Millisecond || t.Window > 10*time.Second {
+		return fmt.Errorf("pressure trigger window %v is out of [500ms, 10s]", t.Window)
+	}
+	if t.Stall <= 0 || t.Stall > t.Window {
+		return fmt.Errorf("pressure trigger stall %v is out of (0, %v]", t.Stall, t.Window)
+	}
+	return nil
+}
+
+// WatchSystemPressure registers the system-wide trigger, see Reader.WatchSystemPressure.
+func WatchSystemPressure(ctx context.Context, trigger PressureTrigger) (<-chan Pressure, error) {
+	return defaultReader.WatchSystemPressure(ctx, trigger)
+}
+
+// WatchSystemPressure registers the trigger in "/proc/pressure/<resource>", the system-wide pressure is
+// delivered on the returned channel each time the trigger fires. The channel is closed when ctx is done.
+func (r *Reader) WatchSystemPressure(ctx context.Context, trigger PressureTrigger) (<-chan Pressure, error) {
+	if err := trigger.validate(); err != nil {
+		return nil, err
+	}
+	return watchPressure(ctx, r.systemPressurePath(trigger.Resource), trigger)
+}
+
+// WatchPressure registers the trigger of the current process's cgroup, see Cgroup.WatchPressure.
+func WatchPressure(ctx context.Context, trigger PressureTrigger) (<-chan Pressure, error) {
+	return defaultReader.WatchPressure(ctx, trigger)
+}
+
+// WatchPressure registers the trigger in "<resource>.pressure" of the cgroup, the pressure of the cgroup is
+// delivered on the returned channel each time the trigger fires. The channel is closed when ctx is done,
+// or the cgroup is removed.
+func (c *Cgroup) WatchPressure(ctx context.Context, trigger PressureTrigger) (<-chan Pressure, error) {
+	if err := trigger.validate(); err != nil {
+		return nil, err
+	}
+	dir, err := c.cgroupDir("")
+	if err != nil {
+		return nil, err
+	}
+	return watchPressure(ctx, path.Join(dir, string(trigger.Resource)+".pressure"), trigger)
+}
@@ -0,0 +1,104 @@
+package cgroup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// pollFd is struct pollfd of poll(2).
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+const (
+	pollIn  = 0x1
+	pollPri = 0x2
+	pollErr = 0x8
+)
+
+// watchPressure registers the trigger in the PSI file, and polls the trigger until ctx is done.
+// See https://www.kernel.org/doc/html/latest/accounting/psi.html#userspace-monitor-usage-example
+func watchPressure(ctx context.Context, file string, trigger PressureTrigger) (<-chan Pressure, error) {
+	fd, err := syscall.Open(file, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %q: %w", file, err)
+	}
+	// the trigger is alive as long as fd is open
+	if _, err = syscall.Write(fd, append([]byte(trigger.String()), 0)); err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("cannot register the pressure trigger %q: %w", trigger, err)
+	}
+	// the pipe wakes up the poll when ctx is done
+	var wake [2]int
+	if err = syscall.Pipe2(wake[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+
+	ch := make(chan Pressure, 1)
+	go func() {
+		defer close(ch)
+		stop := make(chan struct{})
+		woken := make(chan struct{})
+		go func() {
+			defer close(woken)
+			select {
+			case <-ctx.Done():
+				_, _ = syscall.Write(wake[1], []byte{0})
+			case <-stop:
+			}
+		}()
+		defer func() {
+			close(stop)
+			<-woken
+			_ = syscall.Close(fd)
+			_ = syscall.Close(wake[0])
+			_ = syscall.Close(wake[1])
+		}()
+
+		pollPressure(ctx, file, fd, wake[0], ch)
+	}()
+	return ch, nil
+}
+
+// pollPressure delivers the pressure of file into ch each time the trigger fd fires, until wakeFd is readable.
+func pollPressure(ctx context.Context, file string, fd, wakeFd int, ch chan<- Pressure) {
+	fds := []pollFd{{fd: int32(fd), events: pollPri}, {fd: int32(wakeFd), events: pollIn}}
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&fds[0])), uintptr(len(fds)), 0, 0, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		} else if errno != 0 {
+			return
+		}
+		if fds[1].revents != 0 {
+			return
+		}
+		// POLLERR means the monitored cgroup is removed
+		if fds[0].revents&pollErr != 0 {
+			return
+		}
+		if fds[0].revents&pollPri == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return
+		}
+		p, err := parsePressure(string(data))
+		if err != nil {
+			return
+		}
+		select {
+		case ch <- *p:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
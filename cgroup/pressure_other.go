@@ -0,0 +1,12 @@
+//go:build !linux
+
+package cgroup
+
+import (
+	"context"
+	"errors"
+)
+
+func watchPressure(ctx context.Context, file string, trigger PressureTrigger) (<-chan Pressure, error) {
+	return nil, errors.New("pressure triggers are only supported on linux")
+}
@@ -0,0 +1,162 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Reader reads the cgroups from the procfs and sysfs mounted at the given roots.
+//
+// The package-level functions use a default Reader of "/proc" and "/sys", a monitoring sidecar
+// may mount the host's filesystems elsewhere, eg: NewReader("/host/proc", "/host/sys").
+type Reader struct {
+	procRoot string
+	sysRoot  string
+
+	// mu guards the mount table and the mode, which are read once and cached until Refresh.
+	mu        sync.Mutex
+	cached    bool
+	mounts    []Mount
+	mountsErr error
+	mode      CgroupMode
+
+	// Cgroup is the cgroup of the current process, it follows the process if it is moved to another cgroup.
+	*Cgroup
+}
+
+// Cgroup is a cgroup read by a Reader, its accessors are the same as the package-level functions.
+type Cgroup struct {
+	r *Reader
+	// pid is the process whose cgroup is read from <procRoot>/<pid>/cgroup at every access, eg: "self".
+	pid string
+	// paths are the cgroup paths of the controllers if pid is empty,
+	// the cgroup v2 unified hierarchy uses the empty controller.
+	paths map[string]string
+}
+
+var defaultReader = NewReader("/proc", "/sys")
+
+// NewReader returns a Reader of the procfs mounted at procRoot and the sysfs mounted at sysRoot.
+func NewReader(procRoot, sysRoot string) *Reader {
+	r := &Reader{
+		procRoot: path.Clean(procRoot),
+		sysRoot:  path.Clean(sysRoot),
+	}
+	r.Cgroup = &Cgroup{r: r, pid: "self"}
+	return r
+}
+
+// ProcRoot returns the path where the procfs is mounted.
+func (r *Reader) ProcRoot() string {
+	return r.procRoot
+}
+
+// SysRoot returns the path where the sysfs is mounted.
+func (r *Reader) SysRoot() string {
+	return r.sysRoot
+}
+
+// Refresh drops the cached mount table and mode of the default reader, see Reader.Refresh.
+func Refresh() {
+	defaultReader.Refresh()
+}
+
+// Refresh drops the cached mount table and mode, they are read again at the next access,
+// eg: after a cgroup hierarchy is mounted or unmounted.
+func (r *Reader) Refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cached = false
+	r.mounts, r.mountsErr, r.mode = nil, nil, ModeNone
+}
+
+// cachedMounts returns the mount table and the mode, which are read at the first access.
+// The returned mounts are shared, they must not be modified.
+func (r *Reader) cachedMounts() ([]Mount, CgroupMode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.cached {
+		r.mounts, r.mountsErr = r.readMounts()
+		r.mode = r.detectMode(r.mounts)
+		r.cached = true
+	}
+	return r.mounts, r.mode, r.mountsErr
+}
+
+// ForPID returns the cgroup of the process pid, see Reader.ForPID.
+func ForPID(pid int) (*Cgroup, error) {
+	return defaultReader.ForPID(pid)
+}
+
+// ForPID returns the cgroup of the process pid from <procRoot>/<pid>/cgroup.
+//
+// The cgroup is resolved once, it is not changed if the process is moved to another cgroup or exits.
+func (r *Reader) ForPID(pid int) (*Cgroup, error) {
+	data, err := os.ReadFile(path.Join(r.procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	return &Cgroup{r: r, paths: parseProcCgroup(string(data))}, nil
+}
+
+// Open returns the cgroup of cgroupPath, see Reader.Open.
+func Open(cgroupPath string) (*Cgroup, error) {
+	return defaultReader.Open(cgroupPath)
+}
+
+// Open returns the cgroup of cgroupPath, which is the path shown in /proc/<pid>/cgroup, eg: /system.slice/foo.service.
+// The path is the same for all the mounted hierarchies.
+func (r *Reader) Open(cgroupPath string) (*Cgroup, error) {
+	if !path.IsAbs(cgroupPath) {
+		return nil, fmt.Errorf("cgroup path %q is not absolute", cgroupPath)
+	}
+	cgroupPath = path.Clean(cgroupPath)
+
+	mounts, _, err := r.cachedMounts()
+	if err != nil {
+		return nil, err
+	}
+	c := &Cgroup{r: r, paths: map[string]string{}}
+	var exists bool
+	for _, m := range mounts {
+		controllers := m.Controllers
+		if m.IsV2() {
+			controllers = []string{""}
+		}
+		for _, controller := range controllers {
+			c.paths[controller] = cgroupPath
+			if dir, err := controllerDir(mounts, controller, cgroupPath); err == nil && !exists {
+				_, err = os.Stat(dir)
+				exists = err == nil
+			}
+		}
+	}
+	if !exists {
+		return nil, fmt.Errorf("cannot find cgroup %q in any hierarchy: %w", cgroupPath, os.ErrNotExist)
+	}
+	return c, nil
+}
+
+// cgroupPaths returns the cgroup paths of the controllers, the cgroup v2 unified hierarchy uses the empty controller.
+func (c *Cgroup) cgroupPaths() (map[string]string, error) {
+	if c.pid == "" {
+		return c.paths, nil
+	}
+	data, err := os.ReadFile(path.Join(c.r.procRoot, c.pid, "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	return parseProcCgroup(string(data)), nil
+}
+
+// sysPath relocates a path under /sys to sysRoot, eg: /sys/fs/cgroup -> /host/sys/fs/cgroup.
+func (r *Reader) sysPath(p string) string {
+	if p != "/sys" && !strings.HasPrefix(p, "/sys/") {
+		return p
+	}
+	return path.Join(r.sysRoot, strings.TrimPrefix(p, "/sys"))
+}
@@ -0,0 +1,104 @@
+package cgroup
+
+import (
+	"sync"
+	"time"
+)
+
+// CPUUsage is the CPU utilisation of a cgroup in a sampling window.
+type CPUUsage struct {
+	// Interval is the wall time of the window.
+	Interval time.Duration `json:"interval" yaml:"interval" mapstructure:"interval"`
+	// Cores is the average number of CPU cores used in the window, eg: 1.5 means 150% of one core.
+	Cores float64 `json:"cores" yaml:"cores" mapstructure:"cores"`
+	// Quota is the number of CPU cores available, see GetCPUQuota.
+	Quota float64 `json:"quota" yaml:"quota" mapstructure:"quota"`
+	// QuotaPercent is the percentage of Quota used, Cores / Quota * 100.
+	QuotaPercent float64 `json:"quota_percent" yaml:"quota_percent" mapstructure:"quota_percent"`
+	// NrPeriods is the number of the enforcement periods elapsed in the window.
+	NrPeriods int64 `json:"nr_periods" yaml:"nr_periods" mapstructure:"nr_periods"`
+	// NrThrottled is the number of the periods throttled in the window.
+	NrThrottled int64 `json:"nr_throttled" yaml:"nr_throttled" mapstructure:"nr_throttled"`
+	// ThrottledRatio is the throttled time divided by the wall time of the window,
+	// the throttled time is summed over the CPUs, so it may be greater than 1.
+	ThrottledRatio float64 `json:"throttled_ratio" yaml:"throttled_ratio" mapstructure:"throttled_ratio"`
+}
+
+// CPUSampler samples the CPU usage counters of a cgroup, each Sample returns the usage since the previous one.
+// It is safe for concurrent use.
+type CPUSampler struct {
+	c *Cgroup
+
+	mu       sync.Mutex
+	last     *CPUStat
+	lastTime time.Time
+}
+
+// NewCPUSampler returns a CPUSampler of the current process, see Cgroup.NewCPUSampler.
+func NewCPUSampler() (*CPUSampler, error) {
+	return defaultReader.NewCPUSampler()
+}
+
+// NewCPUSampler returns a CPUSampler of the cgroup, the first window starts now.
+func (c *Cgroup) NewCPUSampler() (*CPUSampler, error) {
+	stat, err := c.GetCPUStat()
+	if err != nil {
+		return nil, err
+	}
+	return &CPUSampler{c: c, last: stat, lastTime: time.Now()}, nil
+}
+
+// Sample returns the CPU usage since the previous Sample, or since the sampler is created.
+func (s *CPUSampler) Sample() (*CPUUsage, error) {
+	// the counters are read under the lock, so the concurrent samples are stored in the order they are read.
+	s.mu.Lock()
+	stat, err := s.c.GetCPUStat()
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	now := time.Now()
+	last, lastTime := s.last, s.lastTime
+	s.last, s.lastTime = stat, now
+	s.mu.Unlock()
+
+	return newCPUUsage(last, stat, now.Sub(lastTime), s.c.GetCPUQuota()), nil
+}
+
+// SampleCPUUsage returns the CPU usage of the current process in the next interval, see Cgroup.SampleCPUUsage.
+func SampleCPUUsage(interval time.Duration) (*CPUUsage, error) {
+	return defaultReader.SampleCPUUsage(interval)
+}
+
+// SampleCPUUsage reads the CPU usage counters twice over interval, and returns the usage in the interval.
+//   - interval = 0: 1 second
+func (c *Cgroup) SampleCPUUsage(interval time.Duration) (*CPUUsage, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s, err := c.NewCPUSampler()
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(interval)
+	return s.Sample()
+}
+
+func newCPUUsage(prev, cur *CPUStat, interval time.Duration, quota float64) *CPUUsage {
+	u := &CPUUsage{
+		Interval:    interval,
+		Quota:       quota,
+		NrPeriods:   cur.NrPeriods - prev.NrPeriods,
+		NrThrottled: cur.NrThrottled - prev.NrThrottled,
+	}
+	usec := float64(interval.Microseconds())
+	if usec <= 0 {
+		return u
+	}
+	u.Cores = float64(cur.UsageUsec-prev.UsageUsec) / usec
+	u.ThrottledRatio = float64(cur.ThrottledUsec-prev.ThrottledUsec) / usec
+	if quota > 0 {
+		u.QuotaPercent = u.Cores / quota * 100
+	}
+	return u
+}
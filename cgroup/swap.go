@@ -0,0 +1,68 @@
+package cgroup
+
+// GetSwapLimit returns the swap limit of the current process in bytes from "memory.swap.max" in cgroup v2,
+// or "memory.memsw.limit_in_bytes" minus "memory.limit_in_bytes" in cgroup v1.
+//   - Unlimited: the swap is unlimited
+//   - 0: the cgroup cannot swap
+//
+// An error is returned if the swap accounting is disabled, eg: the kernel is booted without swapaccount=1.
+func GetSwapLimit() (int64, error) {
+	return defaultReader.GetSwapLimit()
+}
+
+// GetSwapLimit returns the swap limit in bytes, see GetSwapLimit.
+func (c *Cgroup) GetSwapLimit() (int64, error) {
+	if c.r.Mode() == ModeUnified {
+		// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
+		return c.getStatGeneric("", "memory.swap.max")
+	}
+
+	// See https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt
+	// memsw is the limit of memory+swap, which is not less than the memory limit.
+	memsw, err := c.getStatGeneric("memory", "memory.memsw.limit_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	if memsw >= v1MemoryUnlimited {
+		return Unlimited, nil
+	}
+	mem, err := c.getStatGeneric("memory", "memory.limit_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	if mem >= v1MemoryUnlimited {
+		return memsw, nil
+	}
+	if memsw < mem {
+		return 0, nil
+	}
+	return memsw - mem, nil
+}
+
+// GetSwapUsage returns the swap usage of the current process in bytes from "memory.swap.current" in cgroup v2,
+// or "memory.memsw.usage_in_bytes" minus "memory.usage_in_bytes" in cgroup v1.
+//
+// An error is returned if the swap accounting is disabled.
+func GetSwapUsage() (int64, error) {
+	return defaultReader.GetSwapUsage()
+}
+
+// GetSwapUsage returns the swap usage in bytes, see GetSwapUsage.
+func (c *Cgroup) GetSwapUsage() (int64, error) {
+	if c.r.Mode() == ModeUnified {
+		return c.getStatGeneric("", "memory.swap.current")
+	}
+
+	memsw, err := c.getStatGeneric("memory", "memory.memsw.usage_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	mem, err := c.getStatGeneric("memory", "memory.usage_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	if memsw < mem {
+		return 0, nil
+	}
+	return memsw - mem, nil
+}
@@ -2,6 +2,7 @@ package cgroup
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path"
 	"strconv"
@@ -9,51 +10,175 @@ import (
 )
 
 // get stats from cgroup
-//   - statName: /sys/fs/cgroup/cgroup-subpath/statFileName
-//   - sysfsPrefix: path to /sys/fs/cgroup/
-//   - cgroupPath: path to /proc/self/cgroup
-//   - cgroupGrepLine: line to grep from cgroupPath
-func getStatGeneric(statFileName, sysfsPrefix, cgroupPath, cgroupGrepLine string) (int64, error) {
-	data, err := getFileContents(statFileName, sysfsPrefix, cgroupPath, cgroupGrepLine)
+//   - controller: the hierarchy of the stat, eg: "memory", empty for cgroup v2
+//   - statFileName: <sysRoot>/fs/cgroup/<controller>/<cgroup-subpath>/statFileName
+func (c *Cgroup) getStatGeneric(controller, statFileName string) (int64, error) {
+	data, err := c.getFileContents(controller, statFileName)
 	if err != nil {
 		return 0, err
 	}
+	return parseStatValue(data, statFileName)
+}
+
+// readStatFile reads a single value file, eg: <cgroup-dir>/memory.max
+func readStatFile(file string) (int64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return parseStatValue(string(data), path.Base(file))
+}
+
+// parseStatValue parses the content of a single value file.
+func parseStatValue(data, statFileName string) (int64, error) {
 	data = strings.TrimSpace(data)
+	// cgroup v2 writes "max" for no limit, eg: memory.max, pids.max
+	if data == "max" {
+		return Unlimited, nil
+	}
 	n, err := strconv.ParseInt(data, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("cannot parse %q: %w", cgroupPath, err)
+		return 0, fmt.Errorf("cannot parse %q: %w", statFileName, err)
 	}
 	return n, nil
 }
 
-func getFileContents(statFileName, sysfsPrefix, cgroupPath, cgroupGrepLine string) (string, error) {
-	// try to read "/sys/fs/cgroup/statFileName" first, eg: in docker.
-	// then try to read "/sys/fs/cgroup/cgroup-subpath/statFileName", eg: in host os.
-	filepath := path.Join(sysfsPrefix, statFileName)
-	data, err := os.ReadFile(filepath)
-	if err == nil {
-		return string(data), nil
+// Unlimited is the value of a limit which is not set, eg: "max" in cgroup v2.
+const Unlimited int64 = math.MaxInt64
+
+// v1MemoryUnlimited is the lowest "no limit" value of cgroup v1 memory.limit_in_bytes, which is
+// the max page counter in bytes, eg: 9223372036854771712 with 4K pages.
+const v1MemoryUnlimited = math.MaxInt64 &^ (1<<16 - 1)
+
+// normalizeMemoryLimit returns Unlimited for the "no limit" value of cgroup v1.
+func normalizeMemoryLimit(n int64) int64 {
+	if n >= v1MemoryUnlimited {
+		return Unlimited
 	}
+	return n
+}
 
-	// parse cgroup-subpath from cgroupPath
-	cgroupData, err := os.ReadFile(cgroupPath)
+// getControllerStat reads statFileName of the v1 controller in legacy/hybrid mode, or v2StatFileName in unified mode.
+// v2StatFileName is empty if the stat is not available in cgroup v2. It returns 0 if the stat cannot be read.
+func (c *Cgroup) getControllerStat(controller, statFileName, v2StatFileName string) int64 {
+	if c.r.Mode() != ModeUnified {
+		n, err := c.getStatGeneric(controller, statFileName)
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+
+	if v2StatFileName == "" {
+		return 0
+	}
+
+	// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#interface-files
+	n, err := c.getStatGeneric("", v2StatFileName)
 	if err != nil {
-		return "", err
+		return 0
 	}
-	subPath, err := grepFirstMatch(string(cgroupData), cgroupGrepLine, 2, ":")
+	return n
+}
+
+// getKeyValues reads a flat keyed file in the cgroup directory, eg: memory.stat, cpu.stat
+//
+//	nr_periods 0
+//	nr_throttled 0
+func (c *Cgroup) getKeyValues(controller, statFileName string) (map[string]int64, error) {
+	data, err := c.getFileContents(controller, statFileName)
 	if err != nil {
-		return "", fmt.Errorf("cannot find cgroup path for %q in %q: %w", cgroupGrepLine, cgroupPath, err)
+		return nil, err
 	}
-	// sys/fs/cgroup/cgroup-subpath/statFileName
-	filepath = path.Join(sysfsPrefix, subPath, statFileName)
+	return parseKeyValues(data), nil
+}
+
+// parseKeyValues parses the lines of "key value" into a map, the unparsable values are 0.
+func parseKeyValues(data string) map[string]int64 {
+	m := map[string]int64{}
+	lines := strings.Split(data, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		segments := strings.Split(line, " ")
+		if len(segments) >= 2 {
+			m[segments[0]], _ = strconv.ParseInt(segments[1], 10, 64)
+		}
+	}
+	return m
+}
 
-	data, err = os.ReadFile(filepath)
+// getFileContents reads statFileName in the cgroup directory.
+func (c *Cgroup) getFileContents(controller, statFileName string) (string, error) {
+	dir, err := c.cgroupDir(controller)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path.Join(dir, statFileName))
 	if err != nil {
 		return "", err
 	}
 	return string(data), nil
 }
 
+// cgroupDir returns the directory of the cgroup in the hierarchy of the controller,
+// an empty controller means the cgroup v2 unified hierarchy.
+//
+// The cgroup-subpath, eg: from /proc/self/cgroup, is resolved against the mount point and the mount root
+// from /proc/self/mountinfo, eg: "/sys/fs/cgroup/cpu,cpuacct/<cgroup-subpath>".
+func (c *Cgroup) cgroupDir(controller string) (string, error) {
+	mount, cgroupPath, err := c.resolve(controller)
+	if err != nil {
+		return "", err
+	}
+	return mountDir(mount, cgroupPath), nil
+}
+
+// cgroupNode is a cgroup directory and its cgroup path.
+type cgroupNode struct {
+	dir  string
+	path string
+}
+
+// cgroupAncestors returns the cgroup and its ancestors in the hierarchy of the controller,
+// from the cgroup up to the root of the mount, the ancestors above the mount root are invisible.
+func (c *Cgroup) cgroupAncestors(controller string) ([]cgroupNode, error) {
+	mount, cgroupPath, err := c.resolve(controller)
+	if err != nil {
+		return nil, err
+	}
+	if !isSubPath(mount.Root, cgroupPath) {
+		return []cgroupNode{{dir: mount.MountPoint, path: mount.Root}}, nil
+	}
+	var nodes []cgroupNode
+	for p := cgroupPath; ; p = path.Dir(p) {
+		nodes = append(nodes, cgroupNode{dir: mountDir(mount, p), path: p})
+		if p == mount.Root || p == "/" {
+			return nodes, nil
+		}
+	}
+}
+
+// resolve returns the mount of the controller's hierarchy and the cgroup path in it.
+func (c *Cgroup) resolve(controller string) (*Mount, string, error) {
+	mounts, _, err := c.r.cachedMounts()
+	if err != nil {
+		return nil, "", err
+	}
+	paths, err := c.cgroupPaths()
+	if err != nil {
+		return nil, "", err
+	}
+	cgroupPath, ok := paths[controller]
+	if !ok {
+		return nil, "", fmt.Errorf("cannot find cgroup path of controller %q", controller)
+	}
+	mount, err := findMount(mounts, controller, cgroupPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return mount, cgroupPath, nil
+}
+
 // grepFirstMatch searches match line at data and returns item from it by index with given delimiter.
 func grepFirstMatch(data string, match string, index int, delimiter string) (string, error) {
 	lines := strings.Split(string(data), "\n")
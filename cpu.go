@@ -1,6 +1,7 @@
 package hwstats
 
 import (
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
 	"os"
 	"runtime"
 )
@@ -8,11 +9,13 @@ import (
 // AvailableCPUs returns the number of available CPU cores for the app.
 //
 // The number is rounded to the next integer value if fractional number of CPU cores are available.
+// It honours the pinned cpuset once UpdateGOMAXPROCSToCPUQuota is called.
 func AvailableCPUs() int {
 	return runtime.GOMAXPROCS(-1)
 }
 
-// UpdateGOMAXPROCSToCPUQuota updates GOMAXPROCS to cpuQuota if GOMAXPROCS isn't set in environment var.
+// UpdateGOMAXPROCSToCPUQuota updates GOMAXPROCS to cpuQuota if GOMAXPROCS isn't set in environment var,
+// it never exceeds the number of CPUs in the cgroup cpuset.
 func UpdateGOMAXPROCSToCPUQuota(cpuQuota float64) {
 	if v := os.Getenv("GOMAXPROCS"); v != "" {
 		// Do not override explicitly set GOMAXPROCS.
@@ -24,6 +27,10 @@ func UpdateGOMAXPROCSToCPUQuota(cpuQuota float64) {
 		// There is no sense in setting more GOMAXPROCS than the number of available CPU cores.
 		gomaxprocs = numCPU
 	}
+	if n := cgroup.GetCPUSet().Count(); n > 0 && gomaxprocs > n {
+		// The process is pinned to fewer CPUs than it can see, eg: the cpuset is changed after the start.
+		gomaxprocs = n
+	}
 	if gomaxprocs <= 0 {
 		gomaxprocs = 1
 	}
@@ -2,6 +2,7 @@ package hwstats
 
 import (
 	"fmt"
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
 	"io"
 	"log"
 	"os"
@@ -106,7 +107,24 @@ func DumpMemory(writer io.Writer) {
 	_, _ = fmt.Fprintf(writer, "system-total-memory: %v\n", formatBytes(s.SysTotalMemory))
 	_, _ = fmt.Fprintf(writer, "system-memory-usage: %v\n", formatBytes(s.SysMemoryUsage))
 	_, _ = fmt.Fprintf(writer, "total-memory: %v\n", formatBytes(s.TotalMemory))
+	_, _ = fmt.Fprintf(writer, "total-memory-source: %v\n", s.TotalMemorySource)
 	_, _ = fmt.Fprintf(writer, "memory-usage: %v\n", formatBytes(s.MemoryUsage))
+	if s.SwapLimit >= 0 {
+		_, _ = fmt.Fprintf(writer, "swap-limit: %v\n", formatBytesLimit(s.SwapLimit))
+		_, _ = fmt.Fprintf(writer, "swap-usage: %v\n", formatBytes(s.SwapUsage))
+	}
+	if s.VmRSS > 0 {
+		_, _ = fmt.Fprintf(writer, "process-rss: %v\n", formatBytes(s.VmRSS))
+		_, _ = fmt.Fprintf(writer, "process-rss-peak: %v\n", formatBytes(s.VmHWM))
+		_, _ = fmt.Fprintf(writer, "process-rss-anon: %v\n", formatBytes(s.RssAnon))
+		_, _ = fmt.Fprintf(writer, "process-rss-file: %v\n", formatBytes(s.RssFile))
+		_, _ = fmt.Fprintf(writer, "process-rss-shmem: %v\n", formatBytes(s.RssShmem))
+		_, _ = fmt.Fprintf(writer, "process-vm-size: %v\n", formatBytes(s.VmSize))
+		_, _ = fmt.Fprintf(writer, "process-vm-swap: %v\n", formatBytes(s.VmSwap))
+		_, _ = fmt.Fprintf(writer, "process-pss: %v\n", formatBytes(s.Pss))
+		_, _ = fmt.Fprintf(writer, "process-pss-anon: %v\n", formatBytes(s.PssAnon))
+		_, _ = fmt.Fprintf(writer, "process-private-dirty: %v\n", formatBytes(s.PrivateDirty))
+	}
 	_, _ = fmt.Fprintf(writer, "alloc: %v\n", formatBytes(s.Alloc))
 	_, _ = fmt.Fprintf(writer, "total-alloc: %v\n", formatBytes(s.TotalAlloc))
 	_, _ = fmt.Fprintf(writer, "sys: %v\n", formatBytes(s.Sys))
@@ -147,6 +165,10 @@ func DumpMemory(writer io.Writer) {
 func DumpGoroutine(writer io.Writer) {
 	_, _ = fmt.Fprintf(writer, "goroutines: %v\n", runtime.NumGoroutine())
 	_, _ = fmt.Fprintf(writer, "OS threads: %v\n", pprof.Lookup("threadcreate").Count())
+	// the threads of all the processes in the cgroup count against pids.max
+	if current := cgroup.GetPidsCurrent(); current > 0 {
+		_, _ = fmt.Fprintf(writer, "cgroup pids: %v / %v\n", current, formatLimit(cgroup.GetPidsLimit()))
+	}
 	_, _ = fmt.Fprintf(writer, "GOMAXPROCS: %v\n", runtime.GOMAXPROCS(0))
 	_, _ = fmt.Fprintf(writer, "num CPU: %v\n", runtime.NumCPU())
 }
@@ -164,6 +186,24 @@ func DumpAll(dir string, callback func(path string)) error {
 		}
 	}
 
+	dumpSnapshot(dir, callback)
+
+	// 30s for cpu profile
+	dumpFile(func(writer io.Writer) error {
+		return DumpCPUProfile(writer, 0)
+	}, filepath.Join(dir, "cpu-profile.profile"), callback)
+
+	// 5s for trace profile
+	dumpFile(func(writer io.Writer) error {
+		return DumpTraceProfile(writer, 0)
+	}, filepath.Join(dir, "trace-profile.profile"), callback)
+
+	return nil
+}
+
+// dumpSnapshot dumps the memory, goroutine, stack trace and the profiles which are taken at once into dir,
+// the CPU and trace profiles which take seconds are not included.
+func dumpSnapshot(dir string, callback func(path string)) {
 	dumpFile(func(writer io.Writer) error {
 		DumpMemory(writer)
 		return nil
@@ -197,18 +237,6 @@ func DumpAll(dir string, callback func(path string)) error {
 	dumpFile(func(writer io.Writer) error {
 		return DumpAllocs(writer, 0)
 	}, filepath.Join(dir, "allocs.profile"), callback)
-
-	// 30s for cpu profile
-	dumpFile(func(writer io.Writer) error {
-		return DumpCPUProfile(writer, 0)
-	}, filepath.Join(dir, "cpu-profile.profile"), callback)
-
-	// 5s for trace profile
-	dumpFile(func(writer io.Writer) error {
-		return DumpTraceProfile(writer, 0)
-	}, filepath.Join(dir, "trace-profile.profile"), callback)
-
-	return nil
 }
 
 // dumpFile dumps the result of fn into path. If fn returns an error, it will be written to path.err.
@@ -0,0 +1,86 @@
+package hwstats
+
+import (
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
+	"math"
+	"os"
+	"runtime/debug"
+)
+
+// DefaultMemoryLimitRatio is the ratio of UpdateGoMemoryLimitToCgroup if the ratio isn't in (0, 1].
+const DefaultMemoryLimitRatio = 0.9
+
+type memoryLimitOptions struct {
+	reserve uint64
+}
+
+// MemoryLimitOption is an option of UpdateGoMemoryLimitToCgroup.
+type MemoryLimitOption func(*memoryLimitOptions)
+
+// WithMemoryReserve subtracts reserve bytes from the Go memory limit for the memory which the Go runtime
+// doesn't manage, eg: the cgo allocations, the mmap by the libraries and the sidecar processes in the cgroup.
+func WithMemoryReserve(reserve uint64) MemoryLimitOption {
+	return func(o *memoryLimitOptions) {
+		o.reserve = reserve
+	}
+}
+
+// UpdateGoMemoryLimitToCgroup updates the Go memory limit (debug.SetMemoryLimit) to TotalMemory() * ratio,
+// minus the reserve of WithMemoryReserve, if GOMEMLIMIT isn't set in environment var.
+//   - ratio isn't in (0, 1]: DefaultMemoryLimitRatio
+//
+// The reserve is ignored if it exceeds TotalMemory() * ratio. It returns the Go memory limit after the update,
+// which is unchanged if GOMEMLIMIT is set or the total memory is unknown.
+func UpdateGoMemoryLimitToCgroup(ratio float64, opts ...MemoryLimitOption) int64 {
+	return defaultSource.UpdateGoMemoryLimitToCgroup(ratio, opts...)
+}
+
+// UpdateGoMemoryLimitToCgroup updates the Go memory limit to the TotalMemory of the source, see UpdateGoMemoryLimitToCgroup.
+func (s *Source) UpdateGoMemoryLimitToCgroup(ratio float64, opts ...MemoryLimitOption) int64 {
+	var o memoryLimitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if ratio <= 0 || ratio > 1 {
+		ratio = DefaultMemoryLimitRatio
+	}
+	totalMemory := s.TotalMemory()
+	if totalMemory > math.MaxInt64 {
+		totalMemory = math.MaxInt64
+	}
+	return setGoMemoryLimit(int64(totalMemory), ratio, o.reserve)
+}
+
+// setGoMemoryLimit sets the Go memory limit to goMemoryLimit of the memory limit if GOMEMLIMIT isn't set
+// in environment var, and returns the Go memory limit after the update.
+func setGoMemoryLimit(limit int64, ratio float64, reserve uint64) int64 {
+	if v := os.Getenv("GOMEMLIMIT"); v != "" {
+		// Do not override explicitly set GOMEMLIMIT.
+		return debug.SetMemoryLimit(-1)
+	}
+	if goLimit, ok := goMemoryLimit(limit, SysTotalMemory(), ratio, reserve); ok {
+		debug.SetMemoryLimit(goLimit)
+	}
+	return debug.SetMemoryLimit(-1)
+}
+
+// goMemoryLimit returns the Go memory limit of the memory limit: limit * ratio - reserve.
+// The limit is capped by the host memory, cgroup.Unlimited or <= 0 means the host memory.
+// The reserve is ignored if it exceeds limit * ratio, so the Go memory limit follows a lowered limit.
+// It returns false if both the limit and the host memory are unknown.
+func goMemoryLimit(limit int64, hostMemory uint64, ratio float64, reserve uint64) (int64, bool) {
+	if hostMemory > math.MaxInt64 {
+		hostMemory = math.MaxInt64
+	}
+	if hostMemory > 0 && (limit <= 0 || limit == cgroup.Unlimited || uint64(limit) > hostMemory) {
+		limit = int64(hostMemory)
+	}
+	if limit <= 0 || limit == cgroup.Unlimited {
+		return 0, false
+	}
+	goLimit := int64(float64(limit) * ratio)
+	if reserve < uint64(goLimit) {
+		goLimit -= int64(reserve)
+	}
+	return goLimit, true
+}
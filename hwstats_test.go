@@ -1,15 +1,60 @@
 package hwstats
 
 import (
+	"context"
 	"fmt"
 	"gopkg.in/go-mixed/hwstats.v1/cgroup"
 	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMemory(t *testing.T) {
 	t.Log("SysTotalMemory:", prettyByteSize(SysTotalMemory()))
 	t.Log("SysFreeMemory:", prettyByteSize(SysFreeMemory()))
+	t.Log("SysAvailableMemory:", prettyByteSize(SysAvailableMemory()))
+	t.Log("SysMemoryUsage:", prettyByteSize(SysMemoryUsage()))
+}
+
+func TestParseMemInfo(t *testing.T) {
+	m, err := parseMemInfo("" +
+		"MemTotal:       16316480 kB\n" +
+		"MemFree:         1020480 kB\n" +
+		"MemAvailable:    9791488 kB\n" +
+		"Active(anon):    2048000 kB\n" +
+		"Committed_AS:   12582912 kB\n" +
+		"HugePages_Total:       4\n" +
+		"Hugepagesize:       2048 kB\n" +
+		"Unknown:              16 kB\n")
+	if err != nil {
+		t.Fatalf("parseMemInfo failed: %v", err)
+	}
+	for name, tt := range map[string]struct{ got, want uint64 }{
+		"MemTotal":       {m.MemTotal, 16316480 << 10},
+		"MemFree":        {m.MemFree, 1020480 << 10},
+		"MemAvailable":   {m.MemAvailable, 9791488 << 10},
+		"ActiveAnon":     {m.ActiveAnon, 2048000 << 10},
+		"CommittedAS":    {m.CommittedAS, 12582912 << 10},
+		"HugePagesTotal": {m.HugePagesTotal, 4},
+		"Hugepagesize":   {m.Hugepagesize, 2 << 20},
+		"Fields":         {m.Fields["Unknown"], 16 << 10},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("parseMemInfo %s = %d, want %d", name, tt.got, tt.want)
+		}
+	}
+	if _, err := parseMemInfo("MemTotal: x kB\n"); err == nil {
+		t.Errorf("parseMemInfo want error")
+	}
+}
+
+func TestExplainTotalMemory(t *testing.T) {
+	totalMemory, source := ExplainTotalMemory()
+	t.Logf("TotalMemory: %s from %s", prettyByteSize(totalMemory), source)
 }
 
 func TestCpu(t *testing.T) {
@@ -19,16 +64,58 @@ func TestCpu(t *testing.T) {
 func TestCGroup(t *testing.T) {
 	t.Log("RunInDocker:", cgroup.RunInDocker())
 	t.Log("RunInCgroup:", cgroup.RunInCgroup())
+	t.Logf("Container: %+v", cgroup.DetectContainer())
+	t.Log("Cgroup mode:", cgroup.Mode())
+	if pressure, err := cgroup.GetSystemPressure(cgroup.PressureMemory); err == nil {
+		t.Logf("System Memory Pressure: %+v", *pressure)
+	}
 	if cgroup.RunInCgroup() {
 		t.Logf("Cgroup path: %s", cgroup.CgroupPath())
 		t.Logf("Cgroup CPUQuota: %f", cgroup.GetCPUQuota())
+		t.Logf("Cgroup CPUSet: %s", cgroup.GetCPUSet())
+		cpuStat, err := cgroup.GetCPUStat()
+		if err != nil {
+			t.Errorf("GetCPUStat failed: %v", err)
+		}
+		t.Logf("Cgroup CPU Stat: %+v", cpuStat)
+		cpuUsage, err := cgroup.SampleCPUUsage(100 * time.Millisecond)
+		if err != nil {
+			t.Errorf("SampleCPUUsage failed: %v", err)
+		}
+		t.Logf("Cgroup CPU Usage: %+v", cpuUsage)
 		t.Logf("Cgroup Memory Limit: %d", cgroup.GetMemoryLimit())
 		t.Logf("Cgroup Hierarchical Memory Limit: %d", cgroup.GetHierarchicalMemoryLimit())
-		memStat, err := cgroup.GetMemoryStat()
+		if memLimits, err := cgroup.GetMemoryLimits(); err == nil {
+			t.Logf("Cgroup Memory Limits: %+v", *memLimits)
+		}
+		limit, limitPath := cgroup.EffectiveMemoryLimit()
+		t.Logf("Cgroup Effective Memory Limit: %d (%s)", limit, limitPath)
+		quota, quotaPath := cgroup.EffectiveCPUQuota()
+		t.Logf("Cgroup Effective CPU Quota: %f (%s)", quota, quotaPath)
+		if cgroup.Mode() == cgroup.ModeUnified {
+			memStat, err := cgroup.GetMemoryStatV2()
+			if err != nil {
+				t.Errorf("GetMemoryStatV2 failed: %v", err)
+			}
+			t.Logf("Cgroup Memory Usage: %+v", memStat)
+		} else {
+			memStat, err := cgroup.GetMemoryStat()
+			if err != nil {
+				t.Errorf("GetMemoryStat failed: %v", err)
+			}
+			t.Logf("Cgroup Memory Usage: %+v", memStat)
+		}
+		// the io controller may not be enabled for the cgroup
+		if ioStat, err := cgroup.GetIOStat(); err == nil {
+			t.Logf("Cgroup IO Stat: %+v", ioStat)
+		} else {
+			t.Logf("Cgroup IO Stat: %v", err)
+		}
+		memEvents, err := cgroup.GetMemoryEvents()
 		if err != nil {
-			t.Errorf("GetMemoryStat failed: %v", err)
+			t.Errorf("GetMemoryEvents failed: %v", err)
 		}
-		t.Logf("Cgroup Memory Usage: %+v", memStat)
+		t.Logf("Cgroup Memory Events: %+v", memEvents)
 	}
 }
 
@@ -42,3 +129,280 @@ func prettyByteSize(b uint64) string {
 	}
 	return fmt.Sprintf("%.1fYiB", bf)
 }
+
+func TestWatchLimits(t *testing.T) {
+	if !cgroup.RunInCgroup() {
+		t.Skip("not run in cgroup")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	ch, err := WatchLimits(ctx, WatchLimitsOptions{Interval: 10 * time.Millisecond, UpdateGOMAXPROCS: true})
+	if err != nil {
+		t.Fatalf("WatchLimits failed: %v", err)
+	}
+	for change := range ch {
+		t.Logf("Limit Change: %+v", change)
+	}
+	t.Log("GOMAXPROCS:", AvailableCPUs())
+}
+
+func TestUpdateGoMemoryLimitToCgroup(t *testing.T) {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		t.Skip("GOMEMLIMIT is set")
+	}
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+
+	want := int64(float64(TotalMemory())*0.5) - 1<<20
+	if n := UpdateGoMemoryLimitToCgroup(0.5, WithMemoryReserve(1<<20)); n != want {
+		t.Errorf("UpdateGoMemoryLimitToCgroup() = %d, want %d", n, want)
+	}
+	// the reserve exceeds the limit, it is ignored
+	want = int64(float64(TotalMemory()) * 0.5)
+	if n := UpdateGoMemoryLimitToCgroup(0.5, WithMemoryReserve(math.MaxUint64)); n != want {
+		t.Errorf("UpdateGoMemoryLimitToCgroup() = %d, want %d", n, want)
+	}
+
+	t.Setenv("GOMEMLIMIT", "1GiB")
+	if n := UpdateGoMemoryLimitToCgroup(0.9); n != want {
+		t.Errorf("UpdateGoMemoryLimitToCgroup() with GOMEMLIMIT = %d, want %d", n, want)
+	}
+}
+
+func TestGoMemoryLimit(t *testing.T) {
+	const gib = 1 << 30
+	for _, tt := range []struct {
+		name       string
+		limit      int64
+		hostMemory uint64
+		reserve    uint64
+		wantLimit  int64
+		wantOK     bool
+	}{
+		{"limited", 4 * gib, 8 * gib, 1 << 20, 2*gib - 1<<20, true},
+		{"unlimited is the host memory", cgroup.Unlimited, 8 * gib, 0, 4 * gib, true},
+		{"capped by the host memory", 16 * gib, 8 * gib, 0, 4 * gib, true},
+		{"the reserve exceeds the limit", 1 * gib, 8 * gib, 1 * gib, gib / 2, true},
+		{"unknown", cgroup.Unlimited, 0, 0, 0, false},
+	} {
+		limit, ok := goMemoryLimit(tt.limit, tt.hostMemory, 0.5, tt.reserve)
+		if limit != tt.wantLimit || ok != tt.wantOK {
+			t.Errorf("%s: goMemoryLimit() = %d, %v, want %d, %v", tt.name, limit, ok, tt.wantLimit, tt.wantOK)
+		}
+	}
+}
+
+func TestWatchLimitsUndrained(t *testing.T) {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		t.Skip("GOMEMLIMIT is set")
+	}
+	if SysTotalMemory() < 1<<30 {
+		t.Skip("the host memory is less than 1GiB")
+	}
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+
+	const memoryMaxFile = "sys/fs/cgroup/app/memory.max"
+	dir := t.TempDir()
+	memoryMax := filepath.Join(dir, memoryMaxFile)
+	for name, content := range map[string]string{
+		"proc/self/cgroup":    "0::/app\n",
+		"proc/self/mountinfo": "32 24 0:28 / /sys/fs/cgroup rw,relatime - cgroup2 cgroup2 rw\n",
+		memoryMaxFile:         "1073741824\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewSource(filepath.Join(dir, "proc"), filepath.Join(dir, "sys"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the channel is never drained, the Go memory limit follows every resize
+	if _, err := s.WatchLimits(ctx, WatchLimitsOptions{Interval: 5 * time.Millisecond, MemoryLimitRatio: 0.5}); err != nil {
+		t.Fatalf("WatchLimits failed: %v", err)
+	}
+	for _, limit := range []int64{1 << 30, 512 << 20, 256 << 20} {
+		if err := os.WriteFile(memoryMax, []byte(fmt.Sprintf("%d\n", limit)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for debug.SetMemoryLimit(-1) != limit/2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if n := debug.SetMemoryLimit(-1); n != limit/2 {
+			t.Fatalf("Go memory limit = %d, want %d", n, limit/2)
+		}
+	}
+}
+
+func TestSourceForPID(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"proc/self/cgroup":                           "0::/sidecar\n",
+		"proc/self/status":                           "VmRSS:\t1 kB\n",
+		"proc/self/mountinfo":                        "32 24 0:28 / /sys/fs/cgroup rw,relatime - cgroup2 cgroup2 rw\n",
+		"proc/1234/cgroup":                           "0::/workload\n",
+		"proc/1234/status":                           "VmRSS:\t2048 kB\nRssAnon:\t1024 kB\n",
+		"sys/fs/cgroup/sidecar/memory.stat":          "anon 1\nfile 1\n",
+		"sys/fs/cgroup/workload/memory.stat":         "anon 4096\nfile 1024\ninactive_file 512\n",
+		"sys/fs/cgroup/workload/cgroup.procs":        "1234\n",
+		"sys/fs/cgroup/workload/memory.swap.max":     "max\n",
+		"sys/fs/cgroup/workload/memory.swap.current": "0\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewSource(filepath.Join(dir, "proc"), filepath.Join(dir, "sys")).ForPID(1234)
+	if err != nil {
+		t.Fatalf("ForPID() error: %v", err)
+	}
+	m, err := s.GetProcessMemory()
+	if err != nil || m.VmRSS != 2048<<10 || m.RssAnon != 1024<<10 {
+		t.Errorf("GetProcessMemory() = %+v, %v, want VmRSS %d", m, err, 2048<<10)
+	}
+	if n := s.MemoryUsage(); n != 5120 {
+		t.Errorf("MemoryUsage() = %d, want %d", n, 5120)
+	}
+	if _, err := NewSource(filepath.Join(dir, "proc"), filepath.Join(dir, "sys")).ForPID(1); err == nil {
+		t.Errorf("ForPID() of a missing process should fail")
+	}
+}
+
+func TestTuneMemoryLimit(t *testing.T) {
+	opts := MemoryLimitTunerOptions{Hysteresis: 0.05}
+	const gib = 1 << 30
+	for _, tt := range []struct {
+		name        string
+		usage       uint64
+		reclaimable uint64
+		goMemory    uint64
+		current     int64
+		opts        MemoryLimitTunerOptions
+		wantLimit   int64
+		wantOK      bool
+	}{
+		{"non-Go memory is subtracted", 3 * gib, 0, 1 * gib, math.MaxInt64, opts, 6 * gib, true},
+		{"within the hysteresis", 3 * gib, 0, 1 * gib, 6*gib + 1<<20, opts, 6*gib + 1<<20, false},
+		{"over the hysteresis", 5 * gib, 0, 1 * gib, 6 * gib, opts, 4 * gib, true},
+		{"min bound", 9 * gib, 0, 1 * gib, 6 * gib, opts, 2 * gib, true},
+		{"max bound", 1 * gib, 0, 1 * gib, math.MaxInt64, MemoryLimitTunerOptions{MaxLimit: 4 * gib, Hysteresis: 0.05}, 4 * gib, true},
+		{"max below the default min", 9 * gib, 0, 1 * gib, math.MaxInt64, MemoryLimitTunerOptions{MaxLimit: 1 * gib, Hysteresis: 0.05}, 1 * gib, true},
+		{"max below the min", 1 * gib, 0, 1 * gib, math.MaxInt64, MemoryLimitTunerOptions{MinLimit: 4 * gib, MaxLimit: 3 * gib, Hysteresis: 0.05}, 3 * gib, true},
+		{"page cache is not non-Go memory", 7 * gib, 4 * gib, 1 * gib, math.MaxInt64, opts, 6 * gib, true},
+		{"reclaimable exceeds usage", 1 * gib, 2 * gib, 1 * gib, math.MaxInt64, opts, 8 * gib, true},
+	} {
+		limit, ok := tuneMemoryLimit(8*gib, tt.usage, tt.reclaimable, tt.goMemory, tt.current, tt.opts)
+		if limit != tt.wantLimit || ok != tt.wantOK {
+			t.Errorf("%s: tuneMemoryLimit() = %d, %v, want %d, %v", tt.name, limit, ok, tt.wantLimit, tt.wantOK)
+		}
+	}
+}
+
+func TestMemoryLimitTuner(t *testing.T) {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		t.Skip("GOMEMLIMIT is set")
+	}
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+
+	tuner := NewMemoryLimitTuner(MemoryLimitTunerOptions{Interval: 10 * time.Millisecond})
+	time.Sleep(50 * time.Millisecond)
+	tuner.Stop()
+	tuner.Stop()
+	t.Logf("Tuned Go memory limit: %s", prettyByteSize(uint64(tuner.Limit())))
+}
+
+func TestFormatBytesLimit(t *testing.T) {
+	if s := formatBytesLimit(cgroup.Unlimited); s != "max" {
+		t.Errorf("formatBytesLimit(Unlimited) = %q, want %q", s, "max")
+	}
+	if s, want := formatBytesLimit(1<<30), "1.00GB (1073741824 bytes)"; s != want {
+		t.Errorf("formatBytesLimit(1GiB) = %q, want %q", s, want)
+	}
+}
+
+func TestDumpMemory(t *testing.T) {
+	var b strings.Builder
+	DumpMemory(&b)
+	t.Log(b.String())
+}
+
+func TestDumpGoroutine(t *testing.T) {
+	var b strings.Builder
+	DumpGoroutine(&b)
+	t.Log(b.String())
+}
+
+func TestWatchdog(t *testing.T) {
+	var usage uint64
+	var warnings, criticals int
+	w := newWatchdog(WatchdogOptions{
+		Levels: []WatchdogLevel{
+			{Threshold: 0.9, Actions: []WatchdogAction{CallbackAction(func(WatchdogEvent) { criticals++ }, 0)}},
+			{Threshold: 0.7, Actions: []WatchdogAction{CallbackAction(func(WatchdogEvent) { warnings++ }, time.Second)}},
+		},
+	}, func() (uint64, uint64) { return usage, 100 })
+
+	now := time.Now()
+	for _, tt := range []struct {
+		usage                    uint64
+		elapsed                  time.Duration
+		wantWarnings, wantCritic int
+	}{
+		{50, 0, 0, 0},
+		{70, 0, 1, 0},
+		{95, 100 * time.Millisecond, 1, 1}, // the warning is in the cooldown
+		{95, 2 * time.Second, 2, 1},        // the critical is in the default cooldown of 1 minute
+		{95, 2 * time.Minute, 3, 2},
+	} {
+		usage = tt.usage
+		now = now.Add(tt.elapsed)
+		w.check(now)
+		if warnings != tt.wantWarnings || criticals != tt.wantCritic {
+			t.Errorf("usage %d: warnings, criticals = %d, %d, want %d, %d",
+				tt.usage, warnings, criticals, tt.wantWarnings, tt.wantCritic)
+		}
+	}
+}
+
+func TestWatchdogDump(t *testing.T) {
+	dir := t.TempDir()
+	opts := WatchdogOptions{
+		Levels: []WatchdogLevel{{Threshold: 0, Actions: []WatchdogAction{GCAction(0), DumpAction(dir, 0)}}},
+	}
+	w := newWatchdog(opts, func() (uint64, uint64) { return 1, 1 })
+	now := time.Now()
+	w.check(now)
+	w.check(now.Add(time.Second)) // in the default cooldown of 1 minute
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ReadDir() = %v, %v, want 1 bundle", entries, err)
+	}
+	for _, name := range []string{"memory.txt", "heap.profile", "allocs.profile", "goroutine.txt", "stack-trace.profile"} {
+		if _, err := os.Stat(filepath.Join(dir, entries[0].Name(), name)); err != nil {
+			t.Errorf("bundle file %s: %v", name, err)
+		}
+	}
+
+	// the bundles of the same time don't collide
+	now = time.Date(2026, 10, 16, 15, 4, 5, 123456789, time.UTC)
+	for _, want := range []string{"20261016-150405.123", "20261016-150405.123-1", "20261016-150405.123-2"} {
+		if p, err := newBundleDir(dir, now); err != nil || p != filepath.Join(dir, want) {
+			t.Errorf("newBundleDir() = %q, %v, want %q", p, err, filepath.Join(dir, want))
+		}
+	}
+
+	// smoke check of the sampling goroutine, the number of ticks depends on the scheduler
+	opts.Interval = 10 * time.Millisecond
+	opts.Levels[0].Actions = []WatchdogAction{GCAction(0), DumpAction(t.TempDir(), 0)}
+	w = NewWatchdog(opts)
+	time.Sleep(50 * time.Millisecond)
+	w.Stop()
+	w.Stop()
+}
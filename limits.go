@@ -0,0 +1,80 @@
+package hwstats
+
+import (
+	"context"
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
+	"time"
+)
+
+// WatchLimitsOptions are the options of WatchLimits.
+type WatchLimitsOptions struct {
+	// Interval is the polling interval of the limits, 0 means 1 second.
+	Interval time.Duration
+	// UpdateGOMAXPROCS calls UpdateGOMAXPROCSToCPUQuota with the new CPU quota.
+	UpdateGOMAXPROCS bool
+	// MemoryLimitRatio sets the Go memory limit to the ratio of the new memory limit, eg: 0.9 leaves 10% for the
+	// non-Go memory, 0 disables it. It does nothing if the GOMEMLIMIT environment var is set.
+	MemoryLimitRatio float64
+	// MemoryReserve is subtracted from the Go memory limit for the non-Go memory, eg: cgo, see WithMemoryReserve.
+	MemoryReserve uint64
+}
+
+// WatchLimits watches the cgroup limits of the current process, see Source.WatchLimits.
+func WatchLimits(ctx context.Context, opts WatchLimitsOptions) (<-chan cgroup.LimitChange, error) {
+	return defaultSource.WatchLimits(ctx, opts)
+}
+
+// WatchLimits delivers a LimitChange on the returned channel each time the CPU quota or the memory limit
+// of the cgroup changes, eg: the in-place pod resize of kubernetes. The channel is closed when ctx is done.
+//
+// The GOMAXPROCS and the Go memory limit are updated to the current limits at once and then to each new limits
+// if the options are enabled, whether the channel is drained or not. The changes are coalesced while the receiver
+// is behind, so a LimitChange may span several changes: Old is the limits before the first, New after the last.
+func (s *Source) WatchLimits(ctx context.Context, opts WatchLimitsOptions) (<-chan cgroup.LimitChange, error) {
+	changes, err := s.cg.WatchLimits(ctx, opts.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	apply := func(limits cgroup.Limits) {
+		// the CPU quota is unknown if the cgroup cannot be read, GOMAXPROCS is left as is
+		if opts.UpdateGOMAXPROCS && limits.CPUQuota > 0 {
+			UpdateGOMAXPROCSToCPUQuota(limits.CPUQuota)
+		}
+		if opts.MemoryLimitRatio > 0 {
+			setGoMemoryLimit(limits.MemoryLimit, opts.MemoryLimitRatio, opts.MemoryReserve)
+		}
+	}
+	apply(s.cg.GetLimits())
+
+	ch := make(chan cgroup.LimitChange)
+	go func() {
+		defer close(ch)
+		var pending cgroup.LimitChange
+		var hasPending bool
+		for {
+			// a nil channel blocks, so the send is only selected if a change is pending
+			var out chan<- cgroup.LimitChange
+			if hasPending {
+				out = ch
+			}
+			select {
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				apply(change.New)
+				if !hasPending {
+					pending = change
+				} else {
+					pending.New = change.New
+				}
+				// the limits are changed back before the receiver sees them
+				hasPending = pending.Old != pending.New
+			case out <- pending:
+				hasPending = false
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -28,69 +28,158 @@ func SysFreeMemory() uint64 {
 	return sysFreeMemory()
 }
 
+// SysAvailableMemory returns the system memory available for starting new applications in bytes.
+//
+// It is MemAvailable of /proc/meminfo on linux, which includes the reclaimable page cache and slab,
+// or the free memory if MemAvailable isn't available, see SysFreeMemory.
+func SysAvailableMemory() uint64 {
+	return defaultSource.SysAvailableMemory()
+}
+
+// SysAvailableMemory returns the system memory available in bytes, see SysAvailableMemory.
+func (s *Source) SysAvailableMemory() uint64 {
+	if memInfo, err := s.GetMemInfo(); err == nil {
+		if available, ok := memInfo.Fields["MemAvailable"]; ok {
+			return available
+		}
+	}
+	return SysFreeMemory()
+}
+
 // SysMemoryUsage returns the total used system memory in bytes.
 //
-// The total used memory is installed physical memory size minus reserved
-// areas for other applications running on the same system.
+// The total used memory is installed physical memory size minus the available
+// memory, the reclaimable page cache is not counted as used on linux, see SysAvailableMemory.
 //
 // If used memory size could not be determined, then 0 is returned.
 func SysMemoryUsage() uint64 {
+	return defaultSource.SysMemoryUsage()
+}
+
+// SysMemoryUsage returns the total used system memory in bytes, see SysMemoryUsage.
+func (s *Source) SysMemoryUsage() uint64 {
+	if memInfo, err := s.GetMemInfo(); err == nil {
+		if available, ok := memInfo.Fields["MemAvailable"]; ok && available <= memInfo.MemTotal {
+			return memInfo.MemTotal - available
+		}
+	}
 	return sysMemoryUsage()
 }
 
 // TotalMemory returns the really total memory, if run in cgroup, it will return
-// the cgroup memory limit, otherwise it will return the system total memory
+// the tightest memory limit of the cgroup and its ancestors, otherwise it will return the system total memory
 func TotalMemory() uint64 {
+	return defaultSource.TotalMemory()
+}
+
+// TotalMemory returns the really total memory, see TotalMemory.
+func (s *Source) TotalMemory() uint64 {
+	totalMemory, _ := s.ExplainTotalMemory()
+	return totalMemory
+}
+
+// ExplainTotalMemory returns the really total memory and where it came from, see TotalMemory.
+func ExplainTotalMemory() (uint64, cgroup.LimitSource) {
+	return defaultSource.ExplainTotalMemory()
+}
+
+// ExplainTotalMemory returns the really total memory and where it came from, see TotalMemory.
+// The source is cgroup.OriginHost if the system total memory is less than the cgroup limit.
+func (s *Source) ExplainTotalMemory() (uint64, cgroup.LimitSource) {
 	totalMemory := SysTotalMemory()
 
-	if cgroup.RunInCgroup() {
-		if cgroupMemoryLimit := uint64(cgroup.GetMemoryLimit()); cgroupMemoryLimit > totalMemory {
-			return totalMemory
-		} else {
-			return cgroupMemoryLimit
+	if s.cg.RunInCgroup() {
+		if limit, source := s.cg.ExplainMemoryLimit(); limit != cgroup.Unlimited && uint64(limit) <= totalMemory {
+			return uint64(limit), source
 		}
 	}
 
-	return totalMemory
+	return totalMemory, cgroup.LimitSource{Origin: cgroup.OriginHost}
 }
 
 // MemoryUsage returns the real memory usage, if run in cgroup, it will return
-// the cgroup memory RSS+Cache usage, otherwise it will return the system memory usage
+// the cgroup memory RSS+Cache (Anon+File in cgroup v2) usage, otherwise it will return the system memory usage
 func MemoryUsage() uint64 {
-	if cgroup.RunInCgroup() {
-		if memStat, err := cgroup.GetMemoryStat(); err != nil {
-			return 0
-		} else {
-			return uint64(memStat.Rss + memStat.Cache)
-		}
+	return defaultSource.MemoryUsage()
+}
+
+// MemoryUsage returns the real memory usage, see MemoryUsage.
+func (s *Source) MemoryUsage() uint64 {
+	if s.cg.RunInCgroup() {
+		usage, _ := s.cgroupMemoryUsage()
+		return usage
 	} else {
-		return SysMemoryUsage()
+		return s.SysMemoryUsage()
 	}
 }
 
+// cgroupMemoryUsage returns the RSS+Cache (Anon+File in cgroup v2) usage of the cgroup, and the inactive page cache
+// in it, which the kernel reclaims first under the limit. They are 0 if the memory stat cannot be read.
+func (s *Source) cgroupMemoryUsage() (usage, inactiveFile uint64) {
+	if s.cgroup.Mode() == cgroup.ModeUnified {
+		if memStat, err := s.cg.GetMemoryStatV2(); err == nil {
+			return uint64(memStat.Anon + memStat.File), uint64(memStat.InactiveFile)
+		}
+		return 0, 0
+	}
+	if memStat, err := s.cg.GetMemoryStat(); err == nil {
+		return uint64(memStat.Rss + memStat.Cache), uint64(memStat.InactiveFile)
+	}
+	return 0, 0
+}
+
 type MemoryStats struct {
 	// MemStats is the memory statistics of current process.
 	runtime.MemStats
+	// ProcessMemory is the memory of current process seen by the kernel, it is zero if not on linux.
+	ProcessMemory
 	// SysTotalMemory is the total accessible system memory in bytes.
 	SysTotalMemory uint64 `json:"sys_total_memory" yaml:"sys_total_memory"`
 	// SysMemoryUsage is the total used system memory in bytes.
 	SysMemoryUsage uint64 `json:"sys_memory_usage" yaml:"sys_memory_usage"`
 	// TotalMemory is the really total memory, cgroup memory limit or system total memory.
 	TotalMemory uint64 `json:"total_memory" yaml:"total_memory"`
+	// TotalMemorySource explains where TotalMemory came from.
+	TotalMemorySource cgroup.LimitSource `json:"total_memory_source" yaml:"total_memory_source"`
 	// MemoryUsage is the real memory usage, cgroup memory usage or system memory usage.
 	MemoryUsage uint64 `json:"memory_usage" yaml:"memory_usage"`
+	// SwapLimit is the cgroup swap limit in bytes, cgroup.Unlimited means unlimited, 0 means the cgroup cannot swap,
+	// -1 if not run in cgroup or the swap accounting is disabled.
+	SwapLimit int64 `json:"swap_limit" yaml:"swap_limit"`
+	// SwapUsage is the cgroup swap usage in bytes.
+	SwapUsage uint64 `json:"swap_usage" yaml:"swap_usage"`
 }
 
 // GetMemoryStats returns the memory statistics of system,and the current process.
 func GetMemoryStats() MemoryStats {
+	return defaultSource.GetMemoryStats()
+}
+
+// GetMemoryStats returns the memory statistics of system,and the current process, see GetMemoryStats.
+func (s *Source) GetMemoryStats() MemoryStats {
 	var ms runtime.MemStats
 	runtime.ReadMemStats(&ms)
 
-	return MemoryStats{
-		MemStats:       ms,
-		SysTotalMemory: SysFreeMemory(),
-		SysMemoryUsage: SysMemoryUsage(),
-		TotalMemory:    TotalMemory(),
-		MemoryUsage:    MemoryUsage(),
+	totalMemory, totalMemorySource := s.ExplainTotalMemory()
+	stats := MemoryStats{
+		MemStats:          ms,
+		SysTotalMemory:    SysTotalMemory(),
+		SysMemoryUsage:    s.SysMemoryUsage(),
+		TotalMemory:       totalMemory,
+		TotalMemorySource: totalMemorySource,
+		MemoryUsage:       s.MemoryUsage(),
+		SwapLimit:         -1,
+	}
+	if processMemory, err := s.GetProcessMemory(); err == nil {
+		stats.ProcessMemory = *processMemory
+	}
+	if s.cg.RunInCgroup() {
+		if swapLimit, err := s.cg.GetSwapLimit(); err == nil {
+			stats.SwapLimit = swapLimit
+		}
+		if swapUsage, err := s.cg.GetSwapUsage(); err == nil {
+			stats.SwapUsage = uint64(swapUsage)
+		}
 	}
+	return stats
 }
@@ -0,0 +1,201 @@
+package hwstats
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MemInfo is the system memory statistics from /proc/meminfo, the sizes are in bytes.
+// See https://www.kernel.org/doc/html/latest/filesystems/proc.html#meminfo
+type MemInfo struct {
+	// MemTotal is the total usable RAM.
+	MemTotal uint64 `json:"mem_total" yaml:"mem_total"`
+	// MemFree is the RAM left unused by the system.
+	MemFree uint64 `json:"mem_free" yaml:"mem_free"`
+	// MemAvailable is the estimate of the memory available for starting new applications without swapping, since linux 3.14.
+	MemAvailable uint64 `json:"mem_available" yaml:"mem_available"`
+	// Buffers is the temporary storage for the raw disk blocks.
+	Buffers uint64 `json:"buffers" yaml:"buffers"`
+	// Cached is the in-memory cache for the files read from the disk (the page cache), excluding SwapCached.
+	Cached uint64 `json:"cached" yaml:"cached"`
+	// SwapCached is the memory swapped out and back in, which is still in the swap file.
+	SwapCached uint64 `json:"swap_cached" yaml:"swap_cached"`
+	// Active is the memory used recently, which is not reclaimed unless absolutely necessary.
+	Active uint64 `json:"active" yaml:"active"`
+	// Inactive is the memory used less recently, which is more eligible to be reclaimed.
+	Inactive     uint64 `json:"inactive" yaml:"inactive"`
+	ActiveAnon   uint64 `json:"active_anon" yaml:"active_anon"`
+	InactiveAnon uint64 `json:"inactive_anon" yaml:"inactive_anon"`
+	ActiveFile   uint64 `json:"active_file" yaml:"active_file"`
+	InactiveFile uint64 `json:"inactive_file" yaml:"inactive_file"`
+	Unevictable  uint64 `json:"unevictable" yaml:"unevictable"`
+	Mlocked      uint64 `json:"mlocked" yaml:"mlocked"`
+	// SwapTotal is the total amount of the swap space.
+	SwapTotal uint64 `json:"swap_total" yaml:"swap_total"`
+	// SwapFree is the swap space left unused.
+	SwapFree uint64 `json:"swap_free" yaml:"swap_free"`
+	Zswap    uint64 `json:"zswap" yaml:"zswap"`
+	Zswapped uint64 `json:"zswapped" yaml:"zswapped"`
+	// Dirty is the memory waiting to be written back to the disk.
+	Dirty uint64 `json:"dirty" yaml:"dirty"`
+	// Writeback is the memory being written back to the disk.
+	Writeback uint64 `json:"writeback" yaml:"writeback"`
+	// AnonPages is the non-file backed pages mapped into the user-space page tables.
+	AnonPages uint64 `json:"anon_pages" yaml:"anon_pages"`
+	// Mapped is the files mapped into memory, such as the libraries.
+	Mapped uint64 `json:"mapped" yaml:"mapped"`
+	// Shmem is the shared memory and tmpfs.
+	Shmem uint64 `json:"shmem" yaml:"shmem"`
+	// KReclaimable is the kernel allocations which the kernel will reclaim under memory pressure.
+	KReclaimable uint64 `json:"k_reclaimable" yaml:"k_reclaimable"`
+	// Slab is the in-kernel data structures cache.
+	Slab uint64 `json:"slab" yaml:"slab"`
+	// SReclaimable is the part of Slab which can be reclaimed, such as the caches.
+	SReclaimable uint64 `json:"s_reclaimable" yaml:"s_reclaimable"`
+	// SUnreclaim is the part of Slab which cannot be reclaimed under memory pressure.
+	SUnreclaim    uint64 `json:"s_unreclaim" yaml:"s_unreclaim"`
+	KernelStack   uint64 `json:"kernel_stack" yaml:"kernel_stack"`
+	PageTables    uint64 `json:"page_tables" yaml:"page_tables"`
+	SecPageTables uint64 `json:"sec_page_tables" yaml:"sec_page_tables"`
+	NFSUnstable   uint64 `json:"nfs_unstable" yaml:"nfs_unstable"`
+	Bounce        uint64 `json:"bounce" yaml:"bounce"`
+	WritebackTmp  uint64 `json:"writeback_tmp" yaml:"writeback_tmp"`
+	// CommitLimit is the total amount of the memory which can be allocated with the strict overcommit (vm.overcommit_memory=2).
+	CommitLimit uint64 `json:"commit_limit" yaml:"commit_limit"`
+	// CommittedAS is the amount of the memory allocated, even if it is not used yet.
+	CommittedAS       uint64 `json:"committed_as" yaml:"committed_as"`
+	VmallocTotal      uint64 `json:"vmalloc_total" yaml:"vmalloc_total"`
+	VmallocUsed       uint64 `json:"vmalloc_used" yaml:"vmalloc_used"`
+	VmallocChunk      uint64 `json:"vmalloc_chunk" yaml:"vmalloc_chunk"`
+	Percpu            uint64 `json:"percpu" yaml:"percpu"`
+	HardwareCorrupted uint64 `json:"hardware_corrupted" yaml:"hardware_corrupted"`
+	// AnonHugePages is the non-file backed huge pages mapped into the user-space page tables, the transparent huge pages.
+	AnonHugePages  uint64 `json:"anon_huge_pages" yaml:"anon_huge_pages"`
+	ShmemHugePages uint64 `json:"shmem_huge_pages" yaml:"shmem_huge_pages"`
+	ShmemPmdMapped uint64 `json:"shmem_pmd_mapped" yaml:"shmem_pmd_mapped"`
+	FileHugePages  uint64 `json:"file_huge_pages" yaml:"file_huge_pages"`
+	FilePmdMapped  uint64 `json:"file_pmd_mapped" yaml:"file_pmd_mapped"`
+	CmaTotal       uint64 `json:"cma_total" yaml:"cma_total"`
+	CmaFree        uint64 `json:"cma_free" yaml:"cma_free"`
+	// HugePagesTotal is the number of the huge pages in the pool, it is a count rather than bytes.
+	HugePagesTotal uint64 `json:"huge_pages_total" yaml:"huge_pages_total"`
+	// HugePagesFree is the number of the huge pages not yet allocated.
+	HugePagesFree uint64 `json:"huge_pages_free" yaml:"huge_pages_free"`
+	// HugePagesRsvd is the number of the huge pages reserved but not yet allocated.
+	HugePagesRsvd uint64 `json:"huge_pages_rsvd" yaml:"huge_pages_rsvd"`
+	// HugePagesSurp is the number of the surplus huge pages above the pool size.
+	HugePagesSurp uint64 `json:"huge_pages_surp" yaml:"huge_pages_surp"`
+	// Hugepagesize is the default huge page size.
+	Hugepagesize uint64 `json:"hugepagesize" yaml:"hugepagesize"`
+	// Hugetlb is the total memory consumed by the huge pages of all sizes.
+	Hugetlb     uint64 `json:"hugetlb" yaml:"hugetlb"`
+	DirectMap4k uint64 `json:"direct_map_4k" yaml:"direct_map_4k"`
+	DirectMap2M uint64 `json:"direct_map_2m" yaml:"direct_map_2m"`
+	DirectMap1G uint64 `json:"direct_map_1g" yaml:"direct_map_1g"`
+	// Fields are all the fields by the names in /proc/meminfo, including the fields unknown to MemInfo,
+	// eg: "Active(anon)", "HugePages_Total".
+	Fields map[string]uint64 `json:"fields" yaml:"fields"`
+}
+
+// GetMemInfo returns the system memory statistics from /proc/meminfo, only available on linux.
+func GetMemInfo() (*MemInfo, error) {
+	return defaultSource.GetMemInfo()
+}
+
+// GetMemInfo returns the system memory statistics, see GetMemInfo.
+func (s *Source) GetMemInfo() (*MemInfo, error) {
+	data, err := os.ReadFile(path.Join(s.cgroup.ProcRoot(), "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	return parseMemInfo(string(data))
+}
+
+// parseMemInfo parses the content of /proc/meminfo, the sizes in kB are converted to bytes.
+//
+//	MemTotal:       16316480 kB
+//	HugePages_Total:       0
+func parseMemInfo(data string) (*MemInfo, error) {
+	m := &MemInfo{Fields: map[string]uint64{}}
+	fields := map[string]*uint64{
+		"MemTotal":          &m.MemTotal,
+		"MemFree":           &m.MemFree,
+		"MemAvailable":      &m.MemAvailable,
+		"Buffers":           &m.Buffers,
+		"Cached":            &m.Cached,
+		"SwapCached":        &m.SwapCached,
+		"Active":            &m.Active,
+		"Inactive":          &m.Inactive,
+		"Active(anon)":      &m.ActiveAnon,
+		"Inactive(anon)":    &m.InactiveAnon,
+		"Active(file)":      &m.ActiveFile,
+		"Inactive(file)":    &m.InactiveFile,
+		"Unevictable":       &m.Unevictable,
+		"Mlocked":           &m.Mlocked,
+		"SwapTotal":         &m.SwapTotal,
+		"SwapFree":          &m.SwapFree,
+		"Zswap":             &m.Zswap,
+		"Zswapped":          &m.Zswapped,
+		"Dirty":             &m.Dirty,
+		"Writeback":         &m.Writeback,
+		"AnonPages":         &m.AnonPages,
+		"Mapped":            &m.Mapped,
+		"Shmem":             &m.Shmem,
+		"KReclaimable":      &m.KReclaimable,
+		"Slab":              &m.Slab,
+		"SReclaimable":      &m.SReclaimable,
+		"SUnreclaim":        &m.SUnreclaim,
+		"KernelStack":       &m.KernelStack,
+		"PageTables":        &m.PageTables,
+		"SecPageTables":     &m.SecPageTables,
+		"NFS_Unstable":      &m.NFSUnstable,
+		"Bounce":            &m.Bounce,
+		"WritebackTmp":      &m.WritebackTmp,
+		"CommitLimit":       &m.CommitLimit,
+		"Committed_AS":      &m.CommittedAS,
+		"VmallocTotal":      &m.VmallocTotal,
+		"VmallocUsed":       &m.VmallocUsed,
+		"VmallocChunk":      &m.VmallocChunk,
+		"Percpu":            &m.Percpu,
+		"HardwareCorrupted": &m.HardwareCorrupted,
+		"AnonHugePages":     &m.AnonHugePages,
+		"ShmemHugePages":    &m.ShmemHugePages,
+		"ShmemPmdMapped":    &m.ShmemPmdMapped,
+		"FileHugePages":     &m.FileHugePages,
+		"FilePmdMapped":     &m.FilePmdMapped,
+		"CmaTotal":          &m.CmaTotal,
+		"CmaFree":           &m.CmaFree,
+		"HugePages_Total":   &m.HugePagesTotal,
+		"HugePages_Free":    &m.HugePagesFree,
+		"HugePages_Rsvd":    &m.HugePagesRsvd,
+		"HugePages_Surp":    &m.HugePagesSurp,
+		"Hugepagesize":      &m.Hugepagesize,
+		"Hugetlb":           &m.Hugetlb,
+		"DirectMap4k":       &m.DirectMap4k,
+		"DirectMap2M":       &m.DirectMap2M,
+		"DirectMap1G":       &m.DirectMap1G,
+	}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		value, kb := strings.CutSuffix(value, " kB")
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse meminfo %q: %w", line, err)
+		}
+		if kb {
+			n *= 1024
+		}
+		m.Fields[key] = n
+		if field, ok := fields[key]; ok {
+			*field = n
+		}
+	}
+	return m, nil
+}
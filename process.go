@@ -0,0 +1,92 @@
+package hwstats
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ProcessMemory is the memory of the current process seen by the kernel, the sizes are in bytes.
+// It includes the memory invisible to the Go runtime, such as the cgo allocations and the mmap by the libraries.
+// See https://www.kernel.org/doc/html/latest/filesystems/proc.html
+type ProcessMemory struct {
+	// VmRSS is the resident set size, RssAnon + RssFile + RssShmem.
+	VmRSS uint64 `json:"vm_rss" yaml:"vm_rss"`
+	// VmHWM is the peak resident set size.
+	VmHWM uint64 `json:"vm_hwm" yaml:"vm_hwm"`
+	// VmSize is the virtual memory size.
+	VmSize uint64 `json:"vm_size" yaml:"vm_size"`
+	// VmSwap is the anonymous memory swapped out.
+	VmSwap uint64 `json:"vm_swap" yaml:"vm_swap"`
+	// RssAnon is the resident anonymous memory, eg: the Go heap and the C heap.
+	RssAnon uint64 `json:"rss_anon" yaml:"rss_anon"`
+	// RssFile is the resident file mappings, eg: the executable and the libraries.
+	RssFile uint64 `json:"rss_file" yaml:"rss_file"`
+	// RssShmem is the resident shared memory.
+	RssShmem uint64 `json:"rss_shmem" yaml:"rss_shmem"`
+	// Pss is the proportional set size, the shared pages are divided by the number of the processes sharing them.
+	// It is 0 if smaps_rollup isn't available, which requires linux 4.14.
+	Pss uint64 `json:"pss" yaml:"pss"`
+	// PssAnon is the proportional size of the anonymous memory.
+	PssAnon uint64 `json:"pss_anon" yaml:"pss_anon"`
+	// PrivateDirty is the private pages modified by the process.
+	PrivateDirty uint64 `json:"private_dirty" yaml:"private_dirty"`
+}
+
+// GetProcessMemory returns the memory of the current process from /proc/self/status and /proc/self/smaps_rollup,
+// only available on linux.
+func GetProcessMemory() (*ProcessMemory, error) {
+	return defaultSource.GetProcessMemory()
+}
+
+// GetProcessMemory returns the memory of the process of the source, see GetProcessMemory.
+func (s *Source) GetProcessMemory() (*ProcessMemory, error) {
+	data, err := os.ReadFile(path.Join(s.cgroup.ProcRoot(), s.pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+	status := parseKBFields(string(data))
+	m := &ProcessMemory{
+		VmRSS:    status["VmRSS"],
+		VmHWM:    status["VmHWM"],
+		VmSize:   status["VmSize"],
+		VmSwap:   status["VmSwap"],
+		RssAnon:  status["RssAnon"],
+		RssFile:  status["RssFile"],
+		RssShmem: status["RssShmem"],
+	}
+
+	// smaps_rollup walks all the mappings, it is slower than status but still cheap
+	if data, err := os.ReadFile(path.Join(s.cgroup.ProcRoot(), s.pid, "smaps_rollup")); err == nil {
+		rollup := parseKBFields(string(data))
+		m.Pss = rollup["Pss"]
+		m.PssAnon = rollup["Pss_Anon"]
+		m.PrivateDirty = rollup["Private_Dirty"]
+	}
+	return m, nil
+}
+
+// parseKBFields parses the "Key: N kB" lines in bytes, the other lines are skipped.
+//
+//	VmRSS:	    1660 kB
+//	Pss_Anon:            104 kB
+func parseKBFields(data string) map[string]uint64 {
+	fields := map[string]uint64{}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value, found = strings.CutSuffix(strings.TrimSpace(value), " kB")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[key] = n * 1024
+	}
+	return fields
+}
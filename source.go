@@ -0,0 +1,50 @@
+package hwstats
+
+import (
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
+	"strconv"
+)
+
+// Source reads the stats from the procfs and sysfs mounted at the given roots.
+//
+// The package-level functions use a default Source of "/proc" and "/sys", a monitoring sidecar
+// may mount the host's filesystems elsewhere, eg: NewSource("/host/proc", "/host/sys").
+// The process stats and the cgroup are of "self", which is the sidecar itself, see ForPID for the workload.
+type Source struct {
+	cgroup *cgroup.Reader
+	// cg is the cgroup of the process, the cgroup of "self" follows the process if it is moved.
+	cg *cgroup.Cgroup
+	// pid is the directory of the process under the procfs, eg: "self" or "1234".
+	pid string
+}
+
+var defaultSource = NewSource("/proc", "/sys")
+
+// NewSource returns a Source of the procfs mounted at procRoot and the sysfs mounted at sysRoot.
+func NewSource(procRoot, sysRoot string) *Source {
+	r := cgroup.NewReader(procRoot, sysRoot)
+	return &Source{
+		cgroup: r,
+		cg:     r.Cgroup,
+		pid:    "self",
+	}
+}
+
+// ForPID returns a Source of the process pid in the procfs of the source, eg: a sidecar which mounts the host's /proc
+// at /host/proc reads the workload with NewSource("/host/proc", "/host/sys").ForPID(pid).
+//
+// The cgroup is resolved once, see cgroup.Reader.ForPID. The Go runtime stats, such as MemStats of GetMemoryStats,
+// are still of the current process, and WatchLimits, UpdateGoMemoryLimitToCgroup and the tuner apply the limits of
+// the process pid to the current process.
+func (s *Source) ForPID(pid int) (*Source, error) {
+	cg, err := s.cgroup.ForPID(pid)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{cgroup: s.cgroup, cg: cg, pid: strconv.Itoa(pid)}, nil
+}
+
+// Cgroup returns the cgroup reader of the source.
+func (s *Source) Cgroup() *cgroup.Reader {
+	return s.cgroup
+}
@@ -0,0 +1,162 @@
+package hwstats
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// MemoryLimitTunerOptions are the options of NewMemoryLimitTuner.
+type MemoryLimitTunerOptions struct {
+	// Interval is the tuning interval, 0 means 5 seconds.
+	Interval time.Duration
+	// TargetRatio is the fraction of TotalMemory which the Go memory plus the non-Go memory stays under,
+	// 0 means DefaultMemoryLimitRatio.
+	TargetRatio float64
+	// MinLimit is the lower bound of the Go memory limit in bytes, 0 means a quarter of the target,
+	// so that the GC doesn't thrash when the non-Go memory grows.
+	MinLimit int64
+	// MaxLimit is the upper bound of the Go memory limit in bytes, 0 means the target. It wins over a larger MinLimit.
+	MaxLimit int64
+	// Hysteresis is the relative change of the Go memory limit below which the limit isn't updated,
+	// 0 means 0.05, eg: a limit of 1GiB isn't updated to 1.02GiB.
+	Hysteresis float64
+}
+
+// MemoryLimitTuner adjusts the Go memory limit periodically, so that the Go memory plus the non-Go memory in
+// the cgroup stays under a target fraction of TotalMemory. The non-Go memory is the cgroup memory usage
+// (the process RSS if not run in cgroup) minus the memory of the Go runtime, such as the page cache,
+// the cgo allocations and the sidecar processes sharing the cgroup.
+type MemoryLimitTuner struct {
+	s    *Source
+	opts MemoryLimitTunerOptions
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewMemoryLimitTuner starts a MemoryLimitTuner of the current process, see Source.NewMemoryLimitTuner.
+func NewMemoryLimitTuner(opts MemoryLimitTunerOptions) *MemoryLimitTuner {
+	return defaultSource.NewMemoryLimitTuner(opts)
+}
+
+// NewMemoryLimitTuner starts a MemoryLimitTuner in background, the first tuning is done before it returns.
+// The tuner does nothing if the GOMEMLIMIT environment var is set.
+func (s *Source) NewMemoryLimitTuner(opts MemoryLimitTunerOptions) *MemoryLimitTuner {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.TargetRatio <= 0 || opts.TargetRatio > 1 {
+		opts.TargetRatio = DefaultMemoryLimitRatio
+	}
+	if opts.Hysteresis <= 0 {
+		opts.Hysteresis = 0.05
+	}
+	t := &MemoryLimitTuner{
+		s:    s,
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if v := os.Getenv("GOMEMLIMIT"); v != "" {
+		// Do not override explicitly set GOMEMLIMIT.
+		close(t.done)
+		return t
+	}
+
+	t.tune()
+	go t.run()
+	return t
+}
+
+// Limit returns the current Go memory limit.
+func (t *MemoryLimitTuner) Limit() int64 {
+	return debug.SetMemoryLimit(-1)
+}
+
+// Stop stops the tuner and waits for the running tuning, the Go memory limit is left as is.
+func (t *MemoryLimitTuner) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	<-t.done
+}
+
+func (t *MemoryLimitTuner) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.tune()
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *MemoryLimitTuner) tune() {
+	totalMemory := t.s.TotalMemory()
+	if totalMemory == 0 {
+		return
+	}
+
+	var usage, reclaimable uint64
+	if t.s.cg.RunInCgroup() {
+		// the inactive page cache is reclaimed before the OOM kill, it doesn't take the room of the Go memory
+		usage, reclaimable = t.s.cgroupMemoryUsage()
+	} else if processMemory, err := t.s.GetProcessMemory(); err == nil {
+		usage = processMemory.VmRSS
+	}
+	if usage == 0 {
+		return
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	// the memory mapped by the Go runtime, the released heap is returned to the OS
+	goMemory := ms.Sys - ms.HeapReleased
+
+	current := debug.SetMemoryLimit(-1)
+	if limit, ok := tuneMemoryLimit(float64(totalMemory)*t.opts.TargetRatio, usage, reclaimable, goMemory, current, t.opts); ok {
+		debug.SetMemoryLimit(limit)
+	}
+}
+
+// tuneMemoryLimit returns the Go memory limit which keeps goMemory plus the non-Go memory under target,
+// the reclaimable memory in usage is not the non-Go memory. ok is false if the change from current is
+// within the hysteresis.
+func tuneMemoryLimit(target float64, usage, reclaimable, goMemory uint64, current int64, opts MemoryLimitTunerOptions) (limit int64, ok bool) {
+	var nonGoMemory float64
+	if reclaimable < usage && usage-reclaimable > goMemory {
+		nonGoMemory = float64(usage - reclaimable - goMemory)
+	}
+
+	minLimit, maxLimit := float64(opts.MinLimit), float64(opts.MaxLimit)
+	if minLimit <= 0 {
+		minLimit = target / 4
+	}
+	if maxLimit <= 0 {
+		maxLimit = target
+	}
+	// MaxLimit wins over a larger MinLimit
+	if minLimit > maxLimit {
+		minLimit = maxLimit
+	}
+	l := math.Max(math.Min(target-nonGoMemory, maxLimit), minLimit)
+	if l >= math.MaxInt64 {
+		l = math.MaxInt64
+	}
+	limit = int64(l)
+
+	// the Go memory limit is math.MaxInt64 by default, which is always updated
+	if current > 0 && current != math.MaxInt64 && math.Abs(float64(limit-current)) < float64(current)*opts.Hysteresis {
+		return current, false
+	}
+	return limit, limit != current
+}
@@ -1,6 +1,9 @@
 package hwstats
 
-import "fmt"
+import (
+	"fmt"
+	"gopkg.in/go-mixed/hwstats.v1/cgroup"
+)
 
 var units = []string{" bytes", "KB", "MB", "GB", "TB", "PB"}
 
@@ -18,3 +21,19 @@ func formatBytes(val uint64) string {
 	}
 	return fmt.Sprintf("%d bytes", val)
 }
+
+// formatLimit formats a cgroup limit, cgroup.Unlimited means no limit.
+func formatLimit(val int64) string {
+	if val == cgroup.Unlimited {
+		return "max"
+	}
+	return fmt.Sprint(val)
+}
+
+// formatBytesLimit formats a cgroup limit in bytes, cgroup.Unlimited means no limit.
+func formatBytesLimit(val int64) string {
+	if val == cgroup.Unlimited {
+		return "max"
+	}
+	return formatBytes(uint64(val))
+}
@@ -0,0 +1,241 @@
+package hwstats
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WatchdogEvent is the memory sample which crossed the threshold of a WatchdogLevel.
+type WatchdogEvent struct {
+	// Threshold is the threshold of the level crossed.
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	// Ratio is MemoryUsage / TotalMemory.
+	Ratio float64 `json:"ratio" yaml:"ratio"`
+	// MemoryUsage is the real memory usage, see MemoryUsage.
+	MemoryUsage uint64 `json:"memory_usage" yaml:"memory_usage"`
+	// TotalMemory is the really total memory, see TotalMemory.
+	TotalMemory uint64 `json:"total_memory" yaml:"total_memory"`
+	// Time is the time of the sample.
+	Time time.Time `json:"time" yaml:"time"`
+}
+
+// WatchdogAction is an action of a WatchdogLevel, it runs at most once per Cooldown.
+type WatchdogAction struct {
+	// Name is the name of the action in the logs.
+	Name string
+	// Do runs the action, the error is logged.
+	Do func(e WatchdogEvent) error
+	// Cooldown is the minimum interval between two runs of the action, 0 means 1 minute.
+	Cooldown time.Duration
+}
+
+// CallbackAction returns a WatchdogAction calling fn.
+func CallbackAction(fn func(e WatchdogEvent), cooldown time.Duration) WatchdogAction {
+	return WatchdogAction{
+		Name: "callback",
+		Do: func(e WatchdogEvent) error {
+			fn(e)
+			return nil
+		},
+		Cooldown: cooldown,
+	}
+}
+
+// GCAction returns a WatchdogAction running runtime.GC.
+func GCAction(cooldown time.Duration) WatchdogAction {
+	return WatchdogAction{
+		Name: "gc",
+		Do: func(WatchdogEvent) error {
+			runtime.GC()
+			return nil
+		},
+		Cooldown: cooldown,
+	}
+}
+
+// FreeOSMemoryAction returns a WatchdogAction running debug.FreeOSMemory, which forces a GC and returns
+// as much memory to the OS as possible.
+func FreeOSMemoryAction(cooldown time.Duration) WatchdogAction {
+	return WatchdogAction{
+		Name: "free-os-memory",
+		Do: func(WatchdogEvent) error {
+			debug.FreeOSMemory()
+			return nil
+		},
+		Cooldown: cooldown,
+	}
+}
+
+// DumpAction returns a WatchdogAction writing the files of DumpAll into a new subdirectory of dir named by the time,
+// eg: dir/20261016-150405.123. It skips the CPU and trace profiles which take seconds, so the bundle arrives
+// before the OOM kill.
+func DumpAction(dir string, cooldown time.Duration) WatchdogAction {
+	return WatchdogAction{
+		Name: "dump",
+		Do: func(e WatchdogEvent) error {
+			return dumpBundle(dir, e.Time, func(string) {})
+		},
+		Cooldown: cooldown,
+	}
+}
+
+// WatchdogLevel is a threshold of the memory usage and the actions run when the usage is over it.
+type WatchdogLevel struct {
+	// Threshold is the ratio of MemoryUsage to TotalMemory, eg: 0.8
+	Threshold float64
+	Actions   []WatchdogAction
+}
+
+// WatchdogOptions are the options of NewWatchdog.
+type WatchdogOptions struct {
+	// Interval is the sampling interval, 0 means 1 second.
+	Interval time.Duration
+	// Levels are the thresholds, the actions of all the levels under the usage are run, from the lowest threshold.
+	Levels []WatchdogLevel
+}
+
+// Watchdog samples MemoryUsage / TotalMemory on an interval, and runs the actions of the levels crossed.
+type Watchdog struct {
+	interval time.Duration
+	levels   []WatchdogLevel
+	sample   func() (usage, total uint64)
+
+	// lastRuns are the last run times of the actions by [level][action]
+	lastRuns [][]time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewWatchdog starts a Watchdog of the current process, see Source.NewWatchdog.
+func NewWatchdog(opts WatchdogOptions) *Watchdog {
+	return defaultSource.NewWatchdog(opts)
+}
+
+// NewWatchdog starts a Watchdog in background, the memory usage and the total memory are from MemoryUsage and
+// TotalMemory of the source.
+func (s *Source) NewWatchdog(opts WatchdogOptions) *Watchdog {
+	w := newWatchdog(opts, func() (uint64, uint64) {
+		return s.MemoryUsage(), s.TotalMemory()
+	})
+	go w.run()
+	return w
+}
+
+func newWatchdog(opts WatchdogOptions, sample func() (usage, total uint64)) *Watchdog {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	levels := make([]WatchdogLevel, len(opts.Levels))
+	copy(levels, opts.Levels)
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].Threshold < levels[j].Threshold })
+
+	w := &Watchdog{
+		interval: opts.Interval,
+		levels:   levels,
+		sample:   sample,
+		lastRuns: make([][]time.Time, len(levels)),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for i, level := range levels {
+		w.lastRuns[i] = make([]time.Time, len(level.Actions))
+	}
+	return w
+}
+
+// Stop stops the watchdog and waits for the running actions.
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+func (w *Watchdog) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.check(now)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// check samples the memory and runs the actions of the levels crossed, which are not in the cooldown.
+func (w *Watchdog) check(now time.Time) {
+	usage, total := w.sample()
+	if total == 0 {
+		return
+	}
+	ratio := float64(usage) / float64(total)
+
+	for i, level := range w.levels {
+		if ratio < level.Threshold {
+			break
+		}
+		e := WatchdogEvent{
+			Threshold:   level.Threshold,
+			Ratio:       ratio,
+			MemoryUsage: usage,
+			TotalMemory: total,
+			Time:        now,
+		}
+		for j, action := range level.Actions {
+			cooldown := action.Cooldown
+			if cooldown <= 0 {
+				cooldown = time.Minute
+			}
+			if last := w.lastRuns[i][j]; !last.IsZero() && now.Sub(last) < cooldown {
+				continue
+			}
+			w.lastRuns[i][j] = now
+			if err := action.Do(e); err != nil {
+				log.Printf("watchdog action %s at %.0f%% failed: %v", action.Name, level.Threshold*100, err)
+			}
+		}
+	}
+}
+
+// dumpBundle dumps the snapshot of dumpSnapshot into a new subdirectory of dir named by t, see newBundleDir.
+func dumpBundle(dir string, t time.Time, callback func(path string)) error {
+	bundleDir, err := newBundleDir(dir, t)
+	if err != nil {
+		return err
+	}
+	dumpSnapshot(bundleDir, callback)
+	return nil
+}
+
+// newBundleDir creates a new subdirectory of dir named by t in milliseconds, eg: dir/20261016-150405.123.
+// A sequence number is appended if the directory exists, eg: dir/20261016-150405.123-1
+func newBundleDir(dir string, t time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, t.Format("20060102-150405.000"))
+	p := name
+	for i := 1; ; i++ {
+		err := os.Mkdir(p, 0o755)
+		if err == nil {
+			return p, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", err
+		}
+		p = fmt.Sprintf("%s-%d", name, i)
+	}
+}